@@ -3,10 +3,11 @@ package glhf
 // AttrFormat defines names and types of OpenGL attributes (vertex format, uniform format, etc.).
 //
 // Example:
-//   AttrFormat{{"position", Vec2}, {"color", Vec4}, {"texCoord": Vec2}}
+//   AttrFormat{{"position", Vec2}, {"color", Vec4}, {"texCoord", Vec2}}
 type AttrFormat []Attr
 
-// Size returns the total size of all attributes of the AttrFormat.
+// Size returns the total size of all attributes of the AttrFormat. For a vertex format, this is
+// the stride between two vertices, including any padding.
 func (af AttrFormat) Size() int {
 	total := 0
 	for _, attr := range af {
@@ -15,8 +16,25 @@ func (af AttrFormat) Size() int {
 	return total
 }
 
+// Offsets returns the byte offsets of all attributes of the AttrFormat within a single vertex.
+func (af AttrFormat) Offsets() []int {
+	offsets := make([]int, len(af))
+	next := 0
+	for i, attr := range af {
+		offsets[i] = next
+		next += attr.Type.Size()
+	}
+	return offsets
+}
+
 // Attr represents an arbitrary OpenGL attribute, such as a vertex attribute or a shader
 // uniform attribute.
+//
+// In a vertex format, an attribute with an empty Name is padding, it takes up space, but isn't
+// passed to the shader. This allows placing the attributes at explicit offsets and matching the
+// std140 and std430 layouts of structs in buffers shared with compute shaders, e.g.
+//   AttrFormat{{"position", Vec3}, {"", Float}, {"color", Vec4}}
+// places color at the offset of 16 bytes and has the stride of 32 bytes.
 type Attr struct {
 	Name string
 	Type AttrType
@@ -41,6 +59,25 @@ const (
 	Mat4
 	Mat42
 	Mat43
+	Uint
+	IVec2
+	IVec3
+	IVec4
+	UVec2
+	UVec3
+	UVec4
+
+	// Compact types for vertex attributes only. The shader receives them as floats (vec2 or
+	// vec4). Normalized types map the range of the integer type to [0, 1] (unsigned) or [-1, 1]
+	// (signed). Use the Pack functions to put the values into vertex data.
+	UByte4Norm
+	Byte4Norm
+	UShort2Norm
+	UShort4Norm
+	Short2Norm
+	Short4Norm
+	Half2
+	Half4
 )
 
 // Size returns the size of a type in bytes.
@@ -74,6 +111,18 @@ func (at AttrType) Size() int {
 		return 4 * 2 * 4
 	case Mat43:
 		return 4 * 3 * 4
+	case Uint:
+		return 4
+	case IVec2, UVec2:
+		return 2 * 4
+	case IVec3, UVec3:
+		return 3 * 4
+	case IVec4, UVec4:
+		return 4 * 4
+	case UByte4Norm, Byte4Norm, UShort2Norm, Short2Norm, Half2:
+		return 4
+	case UShort4Norm, Short4Norm, Half4:
+		return 2 * 4
 	default:
 		panic("size of vertex attribute type: invalid type")
 	}
@@ -0,0 +1,124 @@
+package glhf
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// BindingKind is a kind of numbered binding point that shaders refer to, such as a texture unit or
+// a shader storage buffer binding point.
+type BindingKind int
+
+// List of all binding kinds.
+const (
+	TextureUnit BindingKind = iota
+	UniformBufferBinding
+	StorageBufferBinding
+	ImageUnit
+	numBindingKinds
+)
+
+// String returns a human-readable name of the binding kind.
+func (kind BindingKind) String() string {
+	switch kind {
+	case TextureUnit:
+		return "texture unit"
+	case UniformBufferBinding:
+		return "uniform buffer binding"
+	case StorageBufferBinding:
+		return "shader storage buffer binding"
+	case ImageUnit:
+		return "image unit"
+	}
+	return "unknown binding"
+}
+
+// maxLoc returns the OpenGL constant for querying the number of binding points of the kind.
+func (kind BindingKind) maxLoc() uint32 {
+	switch kind {
+	case TextureUnit:
+		return gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS
+	case UniformBufferBinding:
+		return gl.MAX_UNIFORM_BUFFER_BINDINGS
+	case StorageBufferBinding:
+		return gl.MAX_SHADER_STORAGE_BUFFER_BINDINGS
+	case ImageUnit:
+		return gl.MAX_IMAGE_UNITS
+	}
+	panic("invalid binding kind")
+}
+
+// bindings holds which binding points of each kind are in use. The slices are created lazily,
+// because the number of binding points can only be queried after Init.
+var bindings [numBindingKinds][]bool
+
+func bindingsOf(kind BindingKind) []bool {
+	if kind < 0 || kind >= numBindingKinds {
+		panic("invalid binding kind")
+	}
+	if bindings[kind] == nil {
+		var max int32
+		gl.GetIntegerv(kind.maxLoc(), &max)
+		bindings[kind] = make([]bool, max)
+		if kind == TextureUnit && max > 0 {
+			// glhf binds textures to the active unit, 0 by default, to change them
+			bindings[kind][0] = true
+		}
+	}
+	return bindings[kind]
+}
+
+// AcquireBinding finds a free binding point of the given kind, marks it as used and returns its
+// index. An error is returned if all binding points of the kind are in use (or the kind isn't
+// supported by the OpenGL context at all).
+//
+// Binding points are a global resource shared by everything that draws. Materials, post-processing
+// passes, compute jobs and other subsystems that acquire their binding points here instead of
+// hardcoding them can't silently collide with each other. Call ReleaseBinding when the binding
+// point is no longer needed.
+//
+// Texture unit 0 is never returned, glhf itself binds textures there when changing them. The
+// binding points glhf uses internally are acquired here too.
+func AcquireBinding(kind BindingKind) (int, error) {
+	used := bindingsOf(kind)
+	for i := range used {
+		if !used[i] {
+			used[i] = true
+			return i, nil
+		}
+	}
+	return 0, errors.Errorf("failed to acquire binding: all %d of %ss in use", len(used), kind)
+}
+
+// ReserveBinding marks the binding point of the given kind with the given index as used. Use it
+// for binding points hardcoded elsewhere (e.g. by a layout(binding = ...) qualifier in a shader),
+// so that AcquireBinding never returns them. An error is returned if the binding point is already
+// in use or doesn't exist.
+func ReserveBinding(kind BindingKind, index int) error {
+	used := bindingsOf(kind)
+	if index < 0 || index >= len(used) {
+		return errors.Errorf("failed to reserve binding: %s %d out of range", kind, index)
+	}
+	if used[index] {
+		return errors.Errorf("failed to reserve binding: %s %d already in use", kind, index)
+	}
+	used[index] = true
+	return nil
+}
+
+// ReleaseBinding marks the binding point of the given kind with the given index as free again, so
+// that it can be acquired by someone else.
+func ReleaseBinding(kind BindingKind, index int) {
+	used := bindingsOf(kind)
+	if index < 0 || index >= len(used) {
+		panic("release binding: index out of range")
+	}
+	used[index] = false
+}
+
+// BindingInUse returns whether the binding point of the given kind with the given index is
+// currently acquired or reserved.
+func BindingInUse(kind BindingKind, index int) bool {
+	used := bindingsOf(kind)
+	return index >= 0 && index < len(used) && used[index]
+}
@@ -0,0 +1,147 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// BlendEquation represents how the source and the destination, each multiplied by its blend
+// factor, are combined.
+type BlendEquation int
+
+// Here's the list of all blend equations.
+const (
+	BlendAdd             = BlendEquation(gl.FUNC_ADD)              // src + dst
+	BlendSubtract        = BlendEquation(gl.FUNC_SUBTRACT)         // src - dst
+	BlendReverseSubtract = BlendEquation(gl.FUNC_REVERSE_SUBTRACT) // dst - src
+	BlendMin             = BlendEquation(gl.MIN)                   // min(src, dst), ignores factors
+	BlendMax             = BlendEquation(gl.MAX)                   // max(src, dst), ignores factors
+)
+
+// Blend describes how the colors output by shaders are blended with the colors already in the
+// framebuffer. The color and the alpha components are blended separately. A zero value equation
+// means BlendAdd.
+type Blend struct {
+	SrcRGB, DstRGB     BlendFactor
+	SrcAlpha, DstAlpha BlendFactor
+	RGB, Alpha         BlendEquation
+}
+
+// Here's the list of the common blend modes.
+var (
+	// AlphaBlend is the usual blending of colors with straight (non-premultiplied) alpha.
+	AlphaBlend = Blend{
+		SrcRGB: SrcAlpha, DstRGB: OneMinusSrcAlpha,
+		SrcAlpha: One, DstAlpha: OneMinusSrcAlpha,
+	}
+
+	// PremultipliedBlend blends colors with premultiplied alpha, which composes correctly and
+	// doesn't bleed dark fringes around smoothly sampled edges.
+	PremultipliedBlend = Blend{
+		SrcRGB: One, DstRGB: OneMinusSrcAlpha,
+		SrcAlpha: One, DstAlpha: OneMinusSrcAlpha,
+	}
+
+	// AdditiveBlend adds the colors (with premultiplied alpha) together, e.g. for lights, glows
+	// and particles.
+	AdditiveBlend = Blend{
+		SrcRGB: One, DstRGB: One,
+		SrcAlpha: One, DstAlpha: One,
+	}
+
+	// MultiplyBlend multiplies the colors together, e.g. for shadows and tinting.
+	MultiplyBlend = Blend{
+		SrcRGB: DstColor, DstRGB: Zero,
+		SrcAlpha: DstAlpha, DstAlpha: Zero,
+	}
+
+	// ReplaceBlend replaces the colors, which is the same as disabling blending.
+	ReplaceBlend = Blend{
+		SrcRGB: One, DstRGB: Zero,
+		SrcAlpha: One, DstAlpha: Zero,
+	}
+)
+
+func (e BlendEquation) glEquation() uint32 {
+	if e == 0 {
+		return gl.FUNC_ADD
+	}
+	return uint32(e)
+}
+
+// SetBlend sets the blend mode of all color attachments, e.g. SetBlend(PremultipliedBlend).
+func SetBlend(b Blend) {
+	gl.BlendFuncSeparate(uint32(b.SrcRGB), uint32(b.DstRGB), uint32(b.SrcAlpha), uint32(b.DstAlpha))
+	gl.BlendEquationSeparate(b.RGB.glEquation(), b.Alpha.glEquation())
+}
+
+// SetBlendAttachment sets the blend mode of only the i-th color attachment of the current Frame,
+// e.g. to accumulate the weighted colors and the revealage of order-independent transparency in
+// two attachments with different blending.
+//
+// This function requires OpenGL 4.0 or the GL_ARB_draw_buffers_blend extension, see
+// BlendAttachmentSupported, otherwise it panics.
+func SetBlendAttachment(i int, b Blend) {
+	if !BlendAttachmentSupported() {
+		panic("set blend attachment: not supported")
+	}
+	buf := uint32(i)
+	gl.BlendFuncSeparateiARB(buf, uint32(b.SrcRGB), uint32(b.DstRGB), uint32(b.SrcAlpha), uint32(b.DstAlpha))
+	gl.BlendEquationSeparateiARB(buf, b.RGB.glEquation(), b.Alpha.glEquation())
+}
+
+// SetBlendEnabled enables or disables blending of all color attachments. Blending is enabled by
+// Init. With blending disabled, the colors output by shaders simply replace the colors in the
+// framebuffer, which is necessary for integer formats and faster for opaque geometry.
+func SetBlendEnabled(enabled bool) {
+	if enabled {
+		gl.Enable(gl.BLEND)
+	} else {
+		gl.Disable(gl.BLEND)
+	}
+}
+
+// SetBlendAttachmentEnabled enables or disables blending of only the i-th color attachment of the
+// current Frame. It has the same requirements as SetBlendAttachment.
+func SetBlendAttachmentEnabled(i int, enabled bool) {
+	if !BlendAttachmentSupported() {
+		panic("set blend attachment enabled: not supported")
+	}
+	if enabled {
+		gl.Enablei(gl.BLEND, uint32(i))
+	} else {
+		gl.Disablei(gl.BLEND, uint32(i))
+	}
+}
+
+// SetBlendColor sets the constant color used by the ConstantColor and OneMinusConstantColor blend
+// factors, e.g. to fade a whole layer in and out.
+func SetBlendColor(r, g, b, a float32) {
+	gl.BlendColor(r, g, b, a)
+}
+
+// colorMasks is the stack of color masks saved by PushColorMask.
+var colorMasks [][4]bool
+
+// SetColorMask sets which color components are written into the framebuffer when drawing. All of
+// them are by default. Masking all of them out is useful for depth pre-passes and filling the
+// stencil buffer, where only the depth or the stencil should change.
+func SetColorMask(r, g, b, a bool) {
+	gl.ColorMask(r, g, b, a)
+}
+
+// PushColorMask saves the current color mask and sets the new one, see SetColorMask.
+// PopColorMask restores the saved one.
+func PushColorMask(r, g, b, a bool) {
+	var mask [4]bool
+	gl.GetBooleanv(gl.COLOR_WRITEMASK, &mask[0])
+	colorMasks = append(colorMasks, mask)
+	SetColorMask(r, g, b, a)
+}
+
+// PopColorMask restores the color mask saved by the last PushColorMask.
+func PopColorMask() {
+	if len(colorMasks) == 0 {
+		panic("pop color mask: no color mask pushed")
+	}
+	mask := colorMasks[len(colorMasks)-1]
+	colorMasks = colorMasks[:len(colorMasks)-1]
+	SetColorMask(mask[0], mask[1], mask[2], mask[3])
+}
@@ -0,0 +1,197 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// BufferTarget is the purpose of a Buffer, i.e. the OpenGL binding target it's bound to.
+type BufferTarget uint32
+
+// List of all buffer targets.
+const (
+	// ArrayTarget is for vertex data, like the buffers underlying VertexSlices.
+	ArrayTarget = BufferTarget(gl.ARRAY_BUFFER)
+
+	// ElementArrayTarget is for vertex indices. Note, that this binding is part of the state of
+	// the currently bound vertex array, so bind a VertexSlice first.
+	ElementArrayTarget = BufferTarget(gl.ELEMENT_ARRAY_BUFFER)
+
+	// UniformTarget is for uniform blocks. Bind it to a binding point with BindBase or BindRange.
+	UniformTarget = BufferTarget(gl.UNIFORM_BUFFER)
+
+	// StorageTarget is for shader storage blocks. Bind it to a binding point with BindBase or
+	// BindRange. Requires OpenGL 4.3.
+	StorageTarget = BufferTarget(gl.SHADER_STORAGE_BUFFER)
+
+	// DrawIndirectTarget is for indirect draw commands.
+	DrawIndirectTarget = BufferTarget(gl.DRAW_INDIRECT_BUFFER)
+
+	// DispatchIndirectTarget is for indirect compute dispatch commands. Requires OpenGL 4.3.
+	DispatchIndirectTarget = BufferTarget(gl.DISPATCH_INDIRECT_BUFFER)
+
+	// PixelPackTarget is for reading pixels from textures and framebuffers into.
+	PixelPackTarget = BufferTarget(gl.PIXEL_PACK_BUFFER)
+
+	// PixelUnpackTarget is for uploading pixels to textures from.
+	PixelUnpackTarget = BufferTarget(gl.PIXEL_UNPACK_BUFFER)
+)
+
+func (target BufferTarget) restoreLoc() uint32 {
+	switch target {
+	case ArrayTarget:
+		return gl.ARRAY_BUFFER_BINDING
+	case ElementArrayTarget:
+		return gl.ELEMENT_ARRAY_BUFFER_BINDING
+	case UniformTarget:
+		return gl.UNIFORM_BUFFER_BINDING
+	case StorageTarget:
+		return gl.SHADER_STORAGE_BUFFER_BINDING
+	case DrawIndirectTarget:
+		return gl.DRAW_INDIRECT_BUFFER_BINDING
+	case DispatchIndirectTarget:
+		return gl.DISPATCH_INDIRECT_BUFFER_BINDING
+	case PixelPackTarget:
+		return gl.PIXEL_PACK_BUFFER_BINDING
+	case PixelUnpackTarget:
+		return gl.PIXEL_UNPACK_BUFFER_BINDING
+	}
+	panic("invalid buffer target")
+}
+
+// Buffer is a raw OpenGL buffer of bytes. It's the general version of the buffers underlying
+// VertexSlices and the indirect command buffers, usable for index, uniform, storage, indirect and
+// pixel data alike. VertexSlices still manage their own buffers, because of their persistent
+// mapping and planar layouts, but they expose the same BindBase.
+type Buffer struct {
+	buf    binder
+	target BufferTarget
+	size   int
+	usage  BufferUsage
+}
+
+// NewBuffer creates a new Buffer for the given target with the size in bytes. The content is
+// initially zero.
+func NewBuffer(target BufferTarget, size int, usage BufferUsage) *Buffer {
+	b := &Buffer{
+		buf: binder{
+			restoreLoc: target.restoreLoc(),
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(uint32(target), obj)
+			},
+		},
+		target: target,
+		size:   size,
+		usage:  usage,
+	}
+
+	gl.GenBuffers(1, &b.buf.obj)
+
+	b.Begin()
+	if size > 0 {
+		emptyData := make([]byte, size)
+		gl.BufferData(uint32(target), size, gl.Ptr(emptyData), uint32(usage))
+	} else {
+		// avoid setting 0 bytes of buffer data
+		gl.BufferData(uint32(target), 0, nil, uint32(usage))
+	}
+	b.End()
+
+	runtime.SetFinalizer(b, (*Buffer).delete)
+
+	return b
+}
+
+func (b *Buffer) delete() {
+	mainthread.CallNonBlock(b.release)
+}
+
+// Delete deletes the Buffer immediately instead of waiting for the garbage collector. The Buffer
+// must not be used afterwards. Deleting a Buffer multiple times is harmless.
+func (b *Buffer) Delete() {
+	runtime.SetFinalizer(b, nil)
+	b.release()
+}
+
+func (b *Buffer) release() {
+	gl.DeleteBuffers(1, &b.buf.obj)
+	b.buf.invalidate()
+}
+
+// ID returns the OpenGL ID of this Buffer.
+func (b *Buffer) ID() uint32 {
+	return b.buf.obj
+}
+
+// Target returns the target of the Buffer.
+func (b *Buffer) Target() BufferTarget {
+	return b.target
+}
+
+// Size returns the size of the Buffer in bytes.
+func (b *Buffer) Size() int {
+	return b.size
+}
+
+// Usage returns the usage hint the Buffer was created with.
+func (b *Buffer) Usage() BufferUsage {
+	return b.usage
+}
+
+// SetData sets the content of the Buffer starting at the offset (in bytes) to the data.
+//
+// The Buffer must be bound before calling this method.
+func (b *Buffer) SetData(offset int, data []byte) {
+	if offset < 0 || offset+len(data) > b.size {
+		panic("set data: range out of buffer")
+	}
+	if len(data) == 0 {
+		// avoid setting 0 bytes of buffer data
+		return
+	}
+	gl.BufferSubData(uint32(b.target), offset, len(data), gl.Ptr(data))
+}
+
+// Data returns size bytes of the content of the Buffer starting at the offset (in bytes). This
+// reads the buffer back from the GPU, so it stalls until all previous writes to it have finished.
+//
+// The Buffer must be bound before calling this method.
+func (b *Buffer) Data(offset, size int) []byte {
+	if offset < 0 || size < 0 || offset+size > b.size {
+		panic("data: range out of buffer")
+	}
+	data := make([]byte, size)
+	if size == 0 {
+		return data
+	}
+	gl.GetBufferSubData(uint32(b.target), offset, size, gl.Ptr(data))
+	return data
+}
+
+// BindBase binds the whole Buffer to the binding point index of its target. Only makes sense for
+// UniformTarget and StorageTarget.
+func (b *Buffer) BindBase(index int) {
+	gl.BindBufferBase(uint32(b.target), uint32(index), b.buf.obj)
+}
+
+// BindRange binds size bytes of the Buffer starting at the offset to the binding point index of
+// its target. Only makes sense for UniformTarget and StorageTarget. The offset must be a multiple
+// of GL_UNIFORM_BUFFER_OFFSET_ALIGNMENT or GL_SHADER_STORAGE_BUFFER_OFFSET_ALIGNMENT respectively.
+func (b *Buffer) BindRange(index, offset, size int) {
+	if offset < 0 || size < 0 || offset+size > b.size {
+		panic("bind range: range out of buffer")
+	}
+	gl.BindBufferRange(uint32(b.target), uint32(index), b.buf.obj, offset, size)
+}
+
+// Begin binds the Buffer to its target. This is necessary before using it.
+func (b *Buffer) Begin() {
+	b.buf.bind()
+}
+
+// End unbinds the Buffer and restores the previous one.
+func (b *Buffer) End() {
+	b.buf.restore()
+}
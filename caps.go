@@ -0,0 +1,85 @@
+package glhf
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// caps holds the capabilities of the current OpenGL context. It's filled in by Init.
+var caps struct {
+	major, minor int
+	extensions   map[string]bool
+}
+
+func queryCaps() {
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	caps.major, caps.minor = int(major), int(minor)
+
+	var numExtensions int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &numExtensions)
+	caps.extensions = make(map[string]bool, numExtensions)
+	for i := uint32(0); i < uint32(numExtensions); i++ {
+		caps.extensions[gl.GoStr(gl.GetStringi(gl.EXTENSIONS, i))] = true
+	}
+}
+
+// versionAtLeast returns whether the version of the current OpenGL context is at least
+// major.minor.
+func versionAtLeast(major, minor int) bool {
+	return caps.major > major || (caps.major == major && caps.minor >= minor)
+}
+
+// hasExtension returns whether the current OpenGL context supports the named extension, e.g.
+// "GL_ARB_compute_shader".
+func hasExtension(name string) bool {
+	return caps.extensions[name]
+}
+
+// ErrComputeUnavailable is returned when compute shaders are requested, but the current OpenGL
+// context doesn't support them.
+var ErrComputeUnavailable = errors.New("compute shaders require OpenGL 4.3 or the GL_ARB_compute_shader extension")
+
+// ComputeSupported returns whether the current OpenGL context supports compute shaders. That
+// requires OpenGL 4.3 or the GL_ARB_compute_shader extension.
+//
+// Init must be called before this function.
+func ComputeSupported() bool {
+	return versionAtLeast(4, 3) || hasExtension("GL_ARB_compute_shader")
+}
+
+// bufferStorageSupported returns whether immutable buffer storage (glBufferStorage) is
+// supported, which is necessary for persistently mapped buffers.
+func bufferStorageSupported() bool {
+	return versionAtLeast(4, 4) || hasExtension("GL_ARB_buffer_storage")
+}
+
+// MultiDrawIndirectSupported returns whether VertexSlice.MultiDrawIndirect is supported. This
+// requires OpenGL 4.3 or the GL_ARB_multi_draw_indirect extension.
+//
+// Init must be called before this function.
+func MultiDrawIndirectSupported() bool {
+	return versionAtLeast(4, 3) || hasExtension("GL_ARB_multi_draw_indirect")
+}
+
+// invalidateSupported returns whether framebuffer contents can be discarded using
+// glInvalidateFramebuffer.
+func invalidateSupported() bool {
+	return versionAtLeast(4, 3) || hasExtension("GL_ARB_invalidate_subdata")
+}
+
+// BlendAttachmentSupported returns whether SetBlendAttachment is supported. This requires OpenGL
+// 4.0 or the GL_ARB_draw_buffers_blend extension.
+//
+// Init must be called before this function.
+func BlendAttachmentSupported() bool {
+	return versionAtLeast(4, 0) || hasExtension("GL_ARB_draw_buffers_blend")
+}
+
+// anisotropySupported returns whether anisotropic filtering is supported.
+func anisotropySupported() bool {
+	return versionAtLeast(4, 6) ||
+		hasExtension("GL_ARB_texture_filter_anisotropic") ||
+		hasExtension("GL_EXT_texture_filter_anisotropic")
+}
@@ -0,0 +1,196 @@
+package glhf
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// CompressedFormat is a block-compressed format of the pixels of a Texture. Compressed textures
+// take a fraction of the video memory of uncompressed ones and load faster, because the data is
+// uploaded as is, without decoding it on the CPU.
+//
+// Not all formats are supported everywhere, desktop drivers usually support the BC formats,
+// mobile and embedded ones ETC2 and ASTC. Check with Supported before use.
+type CompressedFormat int
+
+// List of all compressed formats.
+const (
+	// BC1 (DXT1) compresses RGB with 1-bit alpha into 8 bytes per 4x4 block.
+	BC1 CompressedFormat = iota
+
+	// BC2 (DXT3) compresses RGBA with explicit 4-bit alpha into 16 bytes per 4x4 block.
+	BC2
+
+	// BC3 (DXT5) compresses RGBA with interpolated alpha into 16 bytes per 4x4 block.
+	BC3
+
+	// BC4 (RGTC1) compresses a single red component into 8 bytes per 4x4 block.
+	BC4
+
+	// BC5 (RGTC2) compresses red and green, e.g. of a normal map, into 16 bytes per 4x4 block.
+	BC5
+
+	// BC7 (BPTC) compresses RGBA with high quality into 16 bytes per 4x4 block.
+	BC7
+
+	// ETC2RGB8 compresses RGB into 8 bytes per 4x4 block.
+	ETC2RGB8
+
+	// ETC2RGBA8 compresses RGBA into 16 bytes per 4x4 block.
+	ETC2RGBA8
+
+	// ASTC4x4 compresses RGBA into 16 bytes per 4x4 block.
+	ASTC4x4
+
+	// ASTC8x8 compresses RGBA into 16 bytes per 8x8 block.
+	ASTC8x8
+)
+
+// Supported returns whether the current OpenGL context supports the compressed format.
+//
+// Init must be called before this method.
+func (f CompressedFormat) Supported() bool {
+	switch f {
+	case BC1, BC2, BC3:
+		return hasExtension("GL_EXT_texture_compression_s3tc")
+	case BC4, BC5:
+		return true // core since OpenGL 3.0
+	case BC7:
+		return versionAtLeast(4, 2) || hasExtension("GL_ARB_texture_compression_bptc")
+	case ETC2RGB8, ETC2RGBA8:
+		return versionAtLeast(4, 3) || hasExtension("GL_ARB_ES3_compatibility")
+	case ASTC4x4, ASTC8x8:
+		return hasExtension("GL_KHR_texture_compression_astc_ldr")
+	}
+	panic("invalid compressed format")
+}
+
+// BlockSize returns the width and height of a single block of the format in pixels.
+func (f CompressedFormat) BlockSize() (w, h int) {
+	if f == ASTC8x8 {
+		return 8, 8
+	}
+	return 4, 4
+}
+
+// BytesPerBlock returns the number of bytes of a single block of the format.
+func (f CompressedFormat) BytesPerBlock() int {
+	switch f {
+	case BC1, BC4, ETC2RGB8:
+		return 8
+	case BC2, BC3, BC5, BC7, ETC2RGBA8, ASTC4x4, ASTC8x8:
+		return 16
+	}
+	panic("invalid compressed format")
+}
+
+// DataSize returns the number of bytes of an image of the given size in the format. Partial
+// blocks at the edges count as whole ones.
+func (f CompressedFormat) DataSize(width, height int) int {
+	bw, bh := f.BlockSize()
+	return (width + bw - 1) / bw * ((height + bh - 1) / bh) * f.BytesPerBlock()
+}
+
+func (f CompressedFormat) internalFormat() uint32 {
+	switch f {
+	case BC1:
+		return gl.COMPRESSED_RGBA_S3TC_DXT1_EXT
+	case BC2:
+		return gl.COMPRESSED_RGBA_S3TC_DXT3_EXT
+	case BC3:
+		return gl.COMPRESSED_RGBA_S3TC_DXT5_EXT
+	case BC4:
+		return gl.COMPRESSED_RED_RGTC1
+	case BC5:
+		return gl.COMPRESSED_RG_RGTC2
+	case BC7:
+		return gl.COMPRESSED_RGBA_BPTC_UNORM_ARB
+	case ETC2RGB8:
+		return gl.COMPRESSED_RGB8_ETC2
+	case ETC2RGBA8:
+		return gl.COMPRESSED_RGBA8_ETC2_EAC
+	case ASTC4x4:
+		return gl.COMPRESSED_RGBA_ASTC_4x4_KHR
+	case ASTC8x8:
+		return gl.COMPRESSED_RGBA_ASTC_8x8_KHR
+	}
+	panic("invalid compressed format")
+}
+
+// NewCompressedTexture creates a new texture with the specified width and height from data
+// compressed in the format. The levels are the mipmap levels of the texture, starting with the
+// full-sized one, each level being half the size of the previous one (rounded down, at least 1).
+// If there's more than one level, the Texture is mipmapped.
+//
+// The data is uploaded as is, so its rows of blocks start from the bottom regardless of the
+// convention (see SetConvention); flip the image before compressing it if necessary.
+//
+// The Format of the returned Texture is RGBA8, which is what Pixels decompresses it to. SetPixels
+// and SetPixelsRowLength can't be used with it.
+//
+// An error is returned if the format isn't supported or if the size of a level is wrong.
+func NewCompressedTexture(width, height int, smooth bool, format CompressedFormat, levels [][]uint8) (*Texture, error) {
+	if !format.Supported() {
+		return nil, errors.New("failed to create compressed texture: format not supported")
+	}
+	if err := checkTextureSize(width, height); err != nil {
+		return nil, errors.Wrap(err, "failed to create compressed texture")
+	}
+	if len(levels) == 0 {
+		return nil, errors.New("failed to create compressed texture: no data")
+	}
+	w, h := width, height
+	for i, data := range levels {
+		if len(data) != format.DataSize(w, h) {
+			return nil, fmt.Errorf("failed to create compressed texture: wrong size of level %d", i)
+		}
+		w, h = mipSize(w), mipSize(h)
+	}
+
+	tex := &Texture{
+		tex: binder{
+			restoreLoc: gl.TEXTURE_BINDING_2D,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(gl.TEXTURE_2D, obj)
+			},
+		},
+		width:      width,
+		height:     height,
+		format:     RGBA8,
+		compressed: true,
+		mipmapped:  len(levels) > 1,
+	}
+
+	gl.GenTextures(1, &tex.tex.obj)
+
+	tex.Begin()
+	defer tex.End()
+
+	w, h = width, height
+	for i, data := range levels {
+		gl.CompressedTexImage2D(
+			gl.TEXTURE_2D,
+			int32(i),
+			format.internalFormat(),
+			int32(w),
+			int32(h),
+			0,
+			int32(len(data)),
+			gl.Ptr(data),
+		)
+		w, h = mipSize(w), mipSize(h)
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(len(levels)-1))
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	tex.SetSmooth(smooth)
+
+	runtime.SetFinalizer(tex, (*Texture).delete)
+
+	return tex, nil
+}
@@ -0,0 +1,262 @@
+package glhf
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Compute is an OpenGL program consisting of a single compute shader stage.
+type Compute struct {
+	program    binder
+	uniformFmt AttrFormat
+	uniformLoc []int32
+
+	profiling     bool
+	pending, free []uint32 // timer queries
+
+	placeholder bool // shared, see ErrorCompute
+}
+
+// NewComputeProgram creates a new program from the specified compute shader source. Unlike
+// Shader, the program has no vertex or fragment stage, so it can't be used for drawing, only for
+// dispatching compute work.
+//
+// Note that computeShader parameter must contain the source code, it's not a filename.
+//
+// If the current OpenGL context doesn't support compute shaders, ErrComputeUnavailable is
+// returned. With the Resilient failure policy, other failures are logged and ErrorCompute is
+// returned instead of an error.
+func NewComputeProgram(uniformFmt AttrFormat, computeShader string) (*Compute, error) {
+	compute, err := newComputeProgram(uniformFmt, computeShader)
+	if err != nil && err != ErrComputeUnavailable && failurePolicy == Resilient {
+		logf("%v", err)
+		return ErrorCompute(uniformFmt), nil
+	}
+	return compute, err
+}
+
+func newComputeProgram(uniformFmt AttrFormat, computeShader string) (*Compute, error) {
+	if !ComputeSupported() {
+		return nil, ErrComputeUnavailable
+	}
+
+	compute := &Compute{
+		program: binder{
+			restoreLoc: gl.CURRENT_PROGRAM,
+			bindFunc: func(obj uint32) {
+				gl.UseProgram(obj)
+			},
+		},
+		uniformFmt: uniformFmt,
+		uniformLoc: make([]int32, len(uniformFmt)),
+	}
+
+	cshader, err := compileShader(gl.COMPUTE_SHADER, computeShader)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling compute shader: %v", err)
+	}
+	defer gl.DeleteShader(cshader)
+
+	compute.program.obj = gl.CreateProgram()
+	gl.AttachShader(compute.program.obj, cshader)
+	err = linkProgram(compute.program.obj)
+	if err != nil {
+		gl.DeleteProgram(compute.program.obj)
+		return nil, err
+	}
+
+	for i, uniform := range uniformFmt {
+		loc := gl.GetUniformLocation(compute.program.obj, gl.Str(uniform.Name+"\x00"))
+		compute.uniformLoc[i] = loc
+	}
+
+	runtime.SetFinalizer(compute, (*Compute).delete)
+
+	return compute, nil
+}
+
+func (c *Compute) delete() {
+	mainthread.CallNonBlock(c.release)
+}
+
+// Delete deletes the Compute program immediately instead of waiting for the garbage collector.
+// The Compute program must not be used afterwards. Deleting it multiple times is harmless.
+// Deleting the shared ErrorCompute does nothing.
+func (c *Compute) Delete() {
+	if c.placeholder {
+		return
+	}
+	runtime.SetFinalizer(c, nil)
+	c.release()
+}
+
+func (c *Compute) release() {
+	gl.DeleteProgram(c.program.obj)
+	c.program.invalidate()
+	queries := append(c.pending, c.free...)
+	if len(queries) > 0 {
+		gl.DeleteQueries(int32(len(queries)), &queries[0])
+	}
+	c.pending, c.free = nil, nil
+}
+
+// ID returns the OpenGL ID of this Compute program.
+func (c *Compute) ID() uint32 {
+	return c.program.obj
+}
+
+// UniformFormat returns the uniform attribute format of this Compute program. Do not change it.
+func (c *Compute) UniformFormat() AttrFormat {
+	return c.uniformFmt
+}
+
+// SetUniformAttr sets the value of a uniform attribute of this Compute program. The attribute is
+// specified by the index in the Compute program's uniform format.
+//
+// If the uniform attribute does not exist in the program, this method returns false. Supported
+// value types are the same as with (*Shader).SetUniformAttr.
+//
+// The Compute program must be bound before calling this method.
+func (c *Compute) SetUniformAttr(uniform int, value interface{}) (ok bool) {
+	if c.uniformLoc[uniform] < 0 {
+		return false
+	}
+	setUniformAttr(c.uniformLoc[uniform], c.uniformFmt[uniform].Type, value)
+	return true
+}
+
+// Dispatch launches x*y*z work groups of the compute shader.
+//
+// The Compute program must be bound before calling this method.
+func (c *Compute) Dispatch(x, y, z int) {
+	c.beginTiming()
+	gl.DispatchCompute(uint32(x), uint32(y), uint32(z))
+	c.endTiming()
+}
+
+// DispatchIndirect launches the compute shader with the number of work groups taken from the i-th
+// command of the DispatchIndirectBuffer. The command is read directly by the GPU, so it may have
+// been written by a previous compute pass.
+//
+// The Compute program must be bound before calling this method.
+func (c *Compute) DispatchIndirect(buf *DispatchIndirectBuffer, i int) {
+	if i < 0 || i >= buf.len {
+		panic("dispatch indirect: index out of range")
+	}
+	buf.Begin()
+	c.beginTiming()
+	gl.DispatchComputeIndirect(i * dispatchIndirectCommandSize)
+	c.endTiming()
+	buf.End()
+}
+
+// SetProfiling sets whether the dispatches of this Compute program should be timed on the GPU.
+//
+// When enabled, each Dispatch and DispatchIndirect is wrapped in a GPU timer query. The measured
+// durations can later be collected with Timings.
+func (c *Compute) SetProfiling(profiling bool) {
+	c.profiling = profiling
+}
+
+// Profiling returns whether the dispatches of this Compute program are timed on the GPU.
+func (c *Compute) Profiling() bool {
+	return c.profiling
+}
+
+// Timings returns the GPU durations of the profiled dispatches which have finished since the
+// last call, in the order of the dispatches. It never waits for the GPU: the timings of
+// dispatches that haven't finished yet are returned by some later call.
+func (c *Compute) Timings() []time.Duration {
+	var timings []time.Duration
+	for len(c.pending) > 0 {
+		query := c.pending[0]
+
+		var available int32
+		gl.GetQueryObjectiv(query, gl.QUERY_RESULT_AVAILABLE, &available)
+		if available == gl.FALSE {
+			break
+		}
+
+		var elapsed uint64
+		gl.GetQueryObjectui64v(query, gl.QUERY_RESULT, &elapsed)
+		timings = append(timings, time.Duration(elapsed))
+
+		c.pending = c.pending[1:]
+		c.free = append(c.free, query)
+	}
+	return timings
+}
+
+func (c *Compute) beginTiming() {
+	if !c.profiling {
+		return
+	}
+	var query uint32
+	if len(c.free) > 0 {
+		query = c.free[len(c.free)-1]
+		c.free = c.free[:len(c.free)-1]
+	} else {
+		gl.GenQueries(1, &query)
+	}
+	c.pending = append(c.pending, query)
+	gl.BeginQuery(gl.TIME_ELAPSED, query)
+}
+
+func (c *Compute) endTiming() {
+	if !c.profiling {
+		return
+	}
+	gl.EndQuery(gl.TIME_ELAPSED)
+}
+
+// Begin binds the Compute program. This is necessary before dispatching it.
+func (c *Compute) Begin() {
+	c.program.bind()
+}
+
+// End unbinds the Compute program and restores the previous one.
+func (c *Compute) End() {
+	c.program.restore()
+}
+
+// WorkGroupLimits describes the limits the current OpenGL context puts on compute shader work
+// groups.
+type WorkGroupLimits struct {
+	// Count is the maximum number of work groups that can be dispatched in each dimension.
+	Count [3]int
+
+	// Size is the maximum local size of a work group in each dimension.
+	Size [3]int
+
+	// Invocations is the maximum total number of invocations in a single work group, that is,
+	// the maximum product of the local size in all three dimensions.
+	Invocations int
+}
+
+// ComputeLimits queries the compute shader work group limits of the current OpenGL context.
+//
+// Use it to choose the local size of a compute shader and the dispatch size portably, instead
+// of relying on values that happen to work on one GPU.
+//
+// If the current OpenGL context doesn't support compute shaders, all limits are zero.
+func ComputeLimits() WorkGroupLimits {
+	var limits WorkGroupLimits
+	if !ComputeSupported() {
+		return limits
+	}
+	for i := 0; i < 3; i++ {
+		var count, size int32
+		gl.GetIntegeri_v(gl.MAX_COMPUTE_WORK_GROUP_COUNT, uint32(i), &count)
+		gl.GetIntegeri_v(gl.MAX_COMPUTE_WORK_GROUP_SIZE, uint32(i), &size)
+		limits.Count[i] = int(count)
+		limits.Size[i] = int(size)
+	}
+	var invocations int32
+	gl.GetIntegerv(gl.MAX_COMPUTE_WORK_GROUP_INVOCATIONS, &invocations)
+	limits.Invocations = int(invocations)
+	return limits
+}
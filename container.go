@@ -0,0 +1,597 @@
+package glhf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"runtime"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// LoadKTX reads a KTX (version 1 or 2) file and creates a Texture from it, with all its mipmap
+// levels. Both compressed (see CompressedFormat) and uncompressed payloads (see TextureFormat) are
+// supported, as long as the current OpenGL context supports the format.
+//
+// The payload is uploaded as is, without any conversion or flipping, so it should be authored in
+// the OpenGL orientation (first row at the bottom). Array textures are loaded by LoadKTXArray.
+// Cubemaps, 3D textures and supercompressed KTX2 files are not supported.
+//
+// With the Resilient failure policy, a failure is logged and CheckerTexture is returned instead of
+// an error.
+func LoadKTX(r io.Reader, smooth bool) (*Texture, error) {
+	tex, err := loadContainerTexture(r, "ktx", parseKTX, smooth)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return CheckerTexture(), nil
+	}
+	return tex, err
+}
+
+// LoadKTXArray is like LoadKTX, except that it creates a TextureArray, so that it supports KTX
+// files with array layers. A file without array layers is loaded as a TextureArray with a single
+// layer.
+//
+// With the Resilient failure policy, a failure is logged and a checkerboard TextureArray with a
+// single layer is returned instead of an error.
+func LoadKTXArray(r io.Reader, smooth bool) (*TextureArray, error) {
+	ta, err := loadContainerTextureArray(r, "ktx", parseKTX, smooth)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return checkerTextureArray(), nil
+	}
+	return ta, err
+}
+
+// parseKTX parses a KTX file of either version.
+func parseKTX(data []byte) (*containerImage, error) {
+	switch {
+	case bytes.HasPrefix(data, ktx1Identifier):
+		return parseKTX1(data)
+	case bytes.HasPrefix(data, ktx2Identifier):
+		return parseKTX2(data)
+	}
+	return nil, errors.New("not a ktx file")
+}
+
+// LoadDDS reads a DDS file and creates a Texture from it, with all its mipmap levels. Both BC
+// compressed (see CompressedFormat) and uncompressed RGBA payloads are supported, including the
+// DX10 extended header, as long as the current OpenGL context supports the format.
+//
+// The payload is uploaded as is, without any conversion or flipping. DDS files are usually
+// authored with the first row at the top, so flip the texture coordinates accordingly. Array
+// textures (with the DX10 header) are loaded by LoadDDSArray. Cubemaps and volume textures are not
+// supported.
+//
+// With the Resilient failure policy, a failure is logged and CheckerTexture is returned instead of
+// an error.
+func LoadDDS(r io.Reader, smooth bool) (*Texture, error) {
+	tex, err := loadContainerTexture(r, "dds", parseDDS, smooth)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return CheckerTexture(), nil
+	}
+	return tex, err
+}
+
+// LoadDDSArray is like LoadDDS, except that it creates a TextureArray, so that it supports DDS
+// files with array layers. A file without array layers is loaded as a TextureArray with a single
+// layer.
+//
+// With the Resilient failure policy, a failure is logged and a checkerboard TextureArray with a
+// single layer is returned instead of an error.
+func LoadDDSArray(r io.Reader, smooth bool) (*TextureArray, error) {
+	ta, err := loadContainerTextureArray(r, "dds", parseDDS, smooth)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return checkerTextureArray(), nil
+	}
+	return ta, err
+}
+
+func loadContainerTexture(r io.Reader, kind string, parse func([]byte) (*containerImage, error), smooth bool) (*Texture, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", kind)
+	}
+	img, err := parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", kind)
+	}
+	tex, err := img.texture(smooth)
+	return tex, errors.Wrapf(err, "failed to load %s", kind)
+}
+
+func loadContainerTextureArray(r io.Reader, kind string, parse func([]byte) (*containerImage, error), smooth bool) (*TextureArray, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", kind)
+	}
+	img, err := parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load %s", kind)
+	}
+	ta, err := img.textureArray(smooth)
+	return ta, errors.Wrapf(err, "failed to load %s", kind)
+}
+
+// containerImage is the content of a texture container file, independent of the file format.
+type containerImage struct {
+	width, height int
+	layers        int // 0 if not an array
+	compressed    bool
+	cformat       CompressedFormat // if compressed
+	format        TextureFormat    // if not compressed
+	levels        [][]uint8        // each containing all layers
+}
+
+// maxContainerSize is the maximum width, height and number of layers accepted from a container
+// file. It's above any maximum texture size, its purpose is to keep the sizes of the levels from
+// overflowing, the limits of the OpenGL context are checked when the texture is created.
+const maxContainerSize = 1 << 16
+
+const maxInt = int(^uint(0) >> 1)
+
+// checkSize returns an error if the size of the image is invalid or too large to compute the sizes
+// of its levels. It must be called by the parsers before any use of levelSize.
+func (img *containerImage) checkSize() error {
+	if img.width <= 0 || img.height <= 0 || img.width > maxContainerSize || img.height > maxContainerSize {
+		return errors.Errorf("invalid size %dx%d", img.width, img.height)
+	}
+	if img.layers < 0 || img.layers > maxContainerSize {
+		return errors.Errorf("invalid number of layers %d", img.layers)
+	}
+	// 16 bytes per pixel is the most of any format, compressed ones take less
+	const maxBytesPerPixel = 16
+	size := uint64(img.width) * uint64(img.height) * maxBytesPerPixel * uint64(img.arrayLayers())
+	if size > uint64(maxInt) {
+		return errors.Errorf("size %dx%d with %d layers too large", img.width, img.height, img.arrayLayers())
+	}
+	return nil
+}
+
+// levelSize returns the size of the data of the i-th mipmap level of a single layer in bytes.
+func (img *containerImage) levelSize(i int) int {
+	w, h := img.width, img.height
+	for ; i > 0; i-- {
+		w, h = mipSize(w), mipSize(h)
+	}
+	if img.compressed {
+		return img.cformat.DataSize(w, h)
+	}
+	return w * h * img.format.BytesPerPixel()
+}
+
+// arrayLayers returns the number of layers of the image, at least 1.
+func (img *containerImage) arrayLayers() int {
+	if img.layers == 0 {
+		return 1
+	}
+	return img.layers
+}
+
+// textureArray creates a TextureArray from the image.
+func (img *containerImage) textureArray(smooth bool) (*TextureArray, error) {
+	if img.compressed && !img.cformat.Supported() {
+		return nil, errors.New("compressed format not supported")
+	}
+	if err := checkTextureSize(img.width, img.height); err != nil {
+		return nil, err
+	}
+	layers := img.arrayLayers()
+	if layers > MaxArrayTextureLayers() {
+		return nil, errors.Errorf("%d layers exceed the maximum of %d", layers, MaxArrayTextureLayers())
+	}
+	for i, data := range img.levels {
+		if len(data) != img.levelSize(i)*layers {
+			return nil, errors.Errorf("wrong size of level %d", i)
+		}
+	}
+
+	ta := &TextureArray{
+		tex: binder{
+			restoreLoc: gl.TEXTURE_BINDING_2D_ARRAY,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(gl.TEXTURE_2D_ARRAY, obj)
+			},
+		},
+		width:      img.width,
+		height:     img.height,
+		layers:     layers,
+		format:     img.format,
+		compressed: img.compressed,
+		mipmapped:  len(img.levels) > 1,
+	}
+	if img.compressed {
+		ta.format = RGBA8
+	}
+
+	gl.GenTextures(1, &ta.tex.obj)
+
+	ta.Begin()
+	defer ta.End()
+
+	restore := pixelStore(false, 1, 0)
+	w, h := img.width, img.height
+	for i, data := range img.levels {
+		if img.compressed {
+			gl.CompressedTexImage3D(
+				gl.TEXTURE_2D_ARRAY,
+				int32(i),
+				img.cformat.internalFormat(),
+				int32(w),
+				int32(h),
+				int32(layers),
+				0,
+				int32(len(data)),
+				pixelsPtr(data),
+			)
+		} else {
+			gl.TexImage3D(
+				gl.TEXTURE_2D_ARRAY,
+				int32(i),
+				img.format.internalFormat(),
+				int32(w),
+				int32(h),
+				int32(layers),
+				0,
+				img.format.pixelFormat(),
+				img.format.pixelType(),
+				pixelsPtr(data),
+			)
+		}
+		w, h = mipSize(w), mipSize(h)
+	}
+	restore()
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAX_LEVEL, int32(len(img.levels)-1))
+
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	ta.SetSmooth(smooth)
+
+	runtime.SetFinalizer(ta, (*TextureArray).delete)
+
+	return ta, nil
+}
+
+// texture creates a Texture from the image.
+func (img *containerImage) texture(smooth bool) (*Texture, error) {
+	if img.layers > 0 {
+		return nil, errors.New("array texture, load it as a texture array")
+	}
+	if img.compressed {
+		return NewCompressedTexture(img.width, img.height, smooth, img.cformat, img.levels)
+	}
+	for i, data := range img.levels {
+		if len(data) != img.levelSize(i) {
+			return nil, errors.Errorf("wrong size of level %d", i)
+		}
+	}
+
+	tex := &Texture{
+		tex: binder{
+			restoreLoc: gl.TEXTURE_BINDING_2D,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(gl.TEXTURE_2D, obj)
+			},
+		},
+		width:     img.width,
+		height:    img.height,
+		format:    img.format,
+		mipmapped: len(img.levels) > 1,
+	}
+
+	gl.GenTextures(1, &tex.tex.obj)
+
+	tex.Begin()
+	defer tex.End()
+
+	restore := pixelStore(false, 1, 0)
+	w, h := img.width, img.height
+	for i, data := range img.levels {
+		gl.TexImage2D(
+			gl.TEXTURE_2D,
+			int32(i),
+			img.format.internalFormat(),
+			int32(w),
+			int32(h),
+			0,
+			img.format.pixelFormat(),
+			img.format.pixelType(),
+			pixelsPtr(data),
+		)
+		w, h = mipSize(w), mipSize(h)
+	}
+	restore()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(len(img.levels)-1))
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	tex.SetSmooth(smooth)
+
+	runtime.SetFinalizer(tex, (*Texture).delete)
+
+	return tex, nil
+}
+
+// setGLFormat sets the format of the image from an OpenGL internal format.
+func (img *containerImage) setGLFormat(internalFormat uint32) error {
+	for f := BC1; f <= ASTC8x8; f++ {
+		if f.internalFormat() == internalFormat {
+			img.compressed, img.cformat = true, f
+			return nil
+		}
+	}
+	for f := RGBA8; f <= R11FG11FB10F; f++ {
+		if uint32(f.internalFormat()) == internalFormat {
+			img.format = f
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported format 0x%x", internalFormat)
+}
+
+var ktx1Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// parseKTX1 parses a KTX version 1 file.
+func parseKTX1(data []byte) (*containerImage, error) {
+	const headerSize = 64
+	if len(data) < headerSize {
+		return nil, errors.New("truncated header")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:]) != 0x04030201 {
+		order = binary.BigEndian
+	}
+	field := func(i int) uint32 {
+		return order.Uint32(data[12+4*i:])
+	}
+	var (
+		internalFormat = field(4)
+		width          = field(6)
+		height         = field(7)
+		depth          = field(8)
+		arrayElements  = field(9)
+		faces          = field(10)
+		levels         = field(11)
+		keyValueBytes  = field(12)
+	)
+	if depth > 0 || faces != 1 {
+		return nil, errors.New("only 2D textures and 2D array textures are supported")
+	}
+	if levels == 0 {
+		levels = 1
+	}
+
+	img := &containerImage{width: int(width), height: int(height), layers: int(arrayElements)}
+	if err := img.checkSize(); err != nil {
+		return nil, err
+	}
+	if err := img.setGLFormat(internalFormat); err != nil {
+		return nil, err
+	}
+
+	offset := headerSize + int(keyValueBytes)
+	for i := 0; i < int(levels); i++ {
+		if offset+4 > len(data) {
+			return nil, errors.Errorf("truncated level %d", i)
+		}
+		size := int(order.Uint32(data[offset:]))
+		offset += 4
+		if offset+size > len(data) {
+			return nil, errors.Errorf("truncated level %d", i)
+		}
+		img.levels = append(img.levels, data[offset:offset+size])
+		offset += (size + 3) &^ 3 // mip padding
+	}
+	return img, nil
+}
+
+var ktx2Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// ktx2Formats maps the Vulkan formats used by KTX2 to compressed formats or texture formats.
+var ktx2Formats = map[uint32]interface{}{
+	9:   R8,
+	16:  RG8,
+	23:  RGB8,
+	37:  RGBA8,
+	43:  SRGBA8,
+	97:  RGBA16F,
+	98:  R32UI,
+	99:  R32I,
+	100: R32F,
+	109: RGBA32F,
+	122: R11FG11FB10F,
+	133: BC1,
+	135: BC2,
+	137: BC3,
+	139: BC4,
+	141: BC5,
+	145: BC7,
+	147: ETC2RGB8,
+	151: ETC2RGBA8,
+	157: ASTC4x4,
+	171: ASTC8x8,
+}
+
+// parseKTX2 parses a KTX version 2 file.
+func parseKTX2(data []byte) (*containerImage, error) {
+	const (
+		headerSize     = 80
+		levelIndexSize = 24
+	)
+	if len(data) < headerSize {
+		return nil, errors.New("truncated header")
+	}
+	le := binary.LittleEndian
+	var (
+		vkFormat         = le.Uint32(data[12:])
+		width            = le.Uint32(data[20:])
+		height           = le.Uint32(data[24:])
+		depth            = le.Uint32(data[28:])
+		layers           = le.Uint32(data[32:])
+		faces            = le.Uint32(data[36:])
+		levels           = le.Uint32(data[40:])
+		supercompression = le.Uint32(data[44:])
+	)
+	if depth > 0 || faces != 1 {
+		return nil, errors.New("only 2D textures and 2D array textures are supported")
+	}
+	if supercompression != 0 {
+		return nil, errors.New("supercompression not supported")
+	}
+	if levels == 0 {
+		levels = 1
+	}
+
+	img := &containerImage{width: int(width), height: int(height), layers: int(layers)}
+	if err := img.checkSize(); err != nil {
+		return nil, err
+	}
+	switch f := ktx2Formats[vkFormat].(type) {
+	case CompressedFormat:
+		img.compressed, img.cformat = true, f
+	case TextureFormat:
+		img.format = f
+	default:
+		return nil, errors.Errorf("unsupported vulkan format %d", vkFormat)
+	}
+
+	if uint64(levels) > uint64(len(data)-headerSize)/levelIndexSize {
+		return nil, errors.New("truncated level index")
+	}
+	for i := 0; i < int(levels); i++ {
+		entry := data[headerSize+i*levelIndexSize:]
+		offset, size := le.Uint64(entry), le.Uint64(entry[8:])
+		// checked this way around, so that huge values can't overflow
+		if size > uint64(len(data)) || offset > uint64(len(data))-size {
+			return nil, errors.Errorf("truncated level %d", i)
+		}
+		img.levels = append(img.levels, data[offset:offset+size])
+	}
+	return img, nil
+}
+
+// ddsFourCCs maps the four character codes of legacy DDS files to compressed formats.
+var ddsFourCCs = map[string]CompressedFormat{
+	"DXT1": BC1,
+	"DXT3": BC2,
+	"DXT5": BC3,
+	"ATI1": BC4,
+	"BC4U": BC4,
+	"ATI2": BC5,
+	"BC5U": BC5,
+}
+
+// dxgiFormats maps the DXGI formats of DDS files with the DX10 header to compressed formats or
+// texture formats.
+var dxgiFormats = map[uint32]interface{}{
+	2:  RGBA32F,
+	10: RGBA16F,
+	26: R11FG11FB10F,
+	28: RGBA8,
+	29: SRGBA8,
+	41: R32F,
+	42: R32UI,
+	43: R32I,
+	49: RG8,
+	61: R8,
+	71: BC1,
+	74: BC2,
+	77: BC3,
+	80: BC4,
+	83: BC5,
+	98: BC7,
+}
+
+// parseDDS parses a DDS file.
+func parseDDS(data []byte) (*containerImage, error) {
+	const (
+		headerSize      = 4 + 124
+		dx10HeaderSize  = 20
+		flagMipmapCount = 0x20000
+		pixelFourCC     = 0x4
+		pixelRGB        = 0x40
+		caps2Cubemap    = 0x200
+		caps2Volume     = 0x200000
+	)
+	if len(data) < headerSize || string(data[:4]) != "DDS " {
+		return nil, errors.New("not a dds file")
+	}
+	le := binary.LittleEndian
+	var (
+		flags       = le.Uint32(data[8:])
+		height      = le.Uint32(data[12:])
+		width       = le.Uint32(data[16:])
+		mipmapCount = le.Uint32(data[28:])
+		pixelFlags  = le.Uint32(data[80:])
+		fourCC      = string(data[84:88])
+		bitCount    = le.Uint32(data[88:])
+		masks       = [4]uint32{le.Uint32(data[92:]), le.Uint32(data[96:]), le.Uint32(data[100:]), le.Uint32(data[104:])}
+		caps2       = le.Uint32(data[112:])
+	)
+	if caps2&(caps2Cubemap|caps2Volume) != 0 {
+		return nil, errors.New("only 2D textures are supported")
+	}
+	levels := 1
+	if flags&flagMipmapCount != 0 && mipmapCount > 0 {
+		levels = int(mipmapCount)
+	}
+	if levels > 32 {
+		return nil, errors.New("too many mipmap levels")
+	}
+
+	img := &containerImage{width: int(width), height: int(height)}
+	offset := headerSize
+	switch {
+	case pixelFlags&pixelFourCC != 0 && fourCC == "DX10":
+		if len(data) < headerSize+dx10HeaderSize {
+			return nil, errors.New("truncated dx10 header")
+		}
+		dxgiFormat, arraySize := le.Uint32(data[headerSize:]), le.Uint32(data[headerSize+12:])
+		if arraySize > 1 {
+			img.layers = int(arraySize)
+		}
+		switch f := dxgiFormats[dxgiFormat].(type) {
+		case CompressedFormat:
+			img.compressed, img.cformat = true, f
+		case TextureFormat:
+			img.format = f
+		default:
+			return nil, errors.Errorf("unsupported dxgi format %d", dxgiFormat)
+		}
+		offset += dx10HeaderSize
+	case pixelFlags&pixelFourCC != 0:
+		f, ok := ddsFourCCs[fourCC]
+		if !ok {
+			return nil, errors.Errorf("unsupported four character code %q", fourCC)
+		}
+		img.compressed, img.cformat = true, f
+	case pixelFlags&pixelRGB != 0 && bitCount == 32 && masks == [4]uint32{0xff, 0xff00, 0xff0000, 0xff000000}:
+		img.format = RGBA8
+	default:
+		return nil, errors.New("unsupported pixel format")
+	}
+
+	if err := img.checkSize(); err != nil {
+		return nil, err
+	}
+
+	// the layers are stored one after another, each with all its levels, while the levels of
+	// containerImage contain all layers
+	img.levels = make([][]uint8, levels)
+	for layer := 0; layer < img.arrayLayers(); layer++ {
+		for i := 0; i < levels; i++ {
+			size := img.levelSize(i)
+			if size > len(data)-offset {
+				return nil, errors.Errorf("truncated level %d", i)
+			}
+			img.levels[i] = append(img.levels[i], data[offset:offset+size]...)
+			offset += size
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,190 @@
+package glhf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+var le = binary.LittleEndian
+
+// ddsFile returns a DDS file with an uncompressed RGBA8 header of the given size and number of
+// mipmap levels, followed by data.
+func ddsFile(width, height, levels uint32, data []byte) []byte {
+	file := make([]byte, 128)
+	copy(file, "DDS ")
+	le.PutUint32(file[4:], 124)
+	le.PutUint32(file[8:], 0x20000)
+	le.PutUint32(file[12:], height)
+	le.PutUint32(file[16:], width)
+	le.PutUint32(file[28:], levels)
+	le.PutUint32(file[80:], 0x40)
+	le.PutUint32(file[88:], 32)
+	le.PutUint32(file[92:], 0xff)
+	le.PutUint32(file[96:], 0xff00)
+	le.PutUint32(file[100:], 0xff0000)
+	le.PutUint32(file[104:], 0xff000000)
+	return append(file, data...)
+}
+
+// ddsArrayFile returns a DDS file with a DX10 header of an RGBA8 array texture, followed by data.
+func ddsArrayFile(width, height, levels, layers uint32, data []byte) []byte {
+	file := ddsFile(width, height, levels, nil)
+	le.PutUint32(file[80:], 0x4)
+	copy(file[84:], "DX10")
+	dx10 := make([]byte, 20)
+	le.PutUint32(dx10[0:], 28)
+	le.PutUint32(dx10[12:], layers)
+	return append(append(file, dx10...), data...)
+}
+
+// sequence returns n bytes counting up from start.
+func sequence(start, n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(start + i)
+	}
+	return data
+}
+
+func TestParseDDS(t *testing.T) {
+	level0, level1 := sequence(0, 2*2*4), sequence(100, 1*1*4)
+	img, err := parseDDS(ddsFile(2, 2, 2, append(append([]byte(nil), level0...), level1...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.width != 2 || img.height != 2 || img.layers != 0 || img.compressed || img.format != RGBA8 {
+		t.Fatalf("wrong image: %dx%d, %d layers, format %v", img.width, img.height, img.layers, img.format)
+	}
+	if len(img.levels) != 2 || !bytes.Equal(img.levels[0], level0) || !bytes.Equal(img.levels[1], level1) {
+		t.Fatalf("wrong levels: %v", img.levels)
+	}
+}
+
+func TestParseDDSArray(t *testing.T) {
+	// the file stores the layers one after another, each with all its levels
+	var data []byte
+	for layer := 0; layer < 2; layer++ {
+		data = append(data, sequence(layer*100, 2*2*4)...)
+		data = append(data, sequence(layer*100+50, 1*1*4)...)
+	}
+	img, err := parseDDS(ddsArrayFile(2, 2, 2, 2, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.layers != 2 || len(img.levels) != 2 {
+		t.Fatalf("wrong image: %d layers, %d levels", img.layers, len(img.levels))
+	}
+	want0 := append(sequence(0, 16), sequence(100, 16)...)
+	want1 := append(sequence(50, 4), sequence(150, 4)...)
+	if !bytes.Equal(img.levels[0], want0) || !bytes.Equal(img.levels[1], want1) {
+		t.Fatalf("levels not reordered: %v", img.levels)
+	}
+}
+
+func TestParseDDSInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated header": ddsFile(2, 2, 1, nil)[:100],
+		"truncated level":  ddsFile(2, 2, 1, sequence(0, 15)),
+		"zero size":        ddsFile(0, 2, 1, nil),
+		"huge size":        ddsFile(0xFFFFFFFF, 0xFFFFFFFF, 1, sequence(0, 64)),
+		"too many levels":  ddsFile(2, 2, 33, sequence(0, 64)),
+		"huge array":       ddsArrayFile(2, 2, 1, 0xFFFFFFFF, sequence(0, 64)),
+	}
+	for name, file := range tests {
+		if _, err := parseDDS(file); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// ktx1File returns a little-endian KTX1 file of an RGBA8 texture with the given size and levels.
+func ktx1File(width, height uint32, levels ...[]byte) []byte {
+	file := make([]byte, 64)
+	copy(file, ktx1Identifier)
+	le.PutUint32(file[12:], 0x04030201)
+	le.PutUint32(file[28:], uint32(RGBA8.internalFormat()))
+	le.PutUint32(file[36:], width)
+	le.PutUint32(file[40:], height)
+	le.PutUint32(file[52:], 1)
+	le.PutUint32(file[56:], uint32(len(levels)))
+	for _, level := range levels {
+		size := make([]byte, 4)
+		le.PutUint32(size, uint32(len(level)))
+		file = append(append(file, size...), level...)
+	}
+	return file
+}
+
+func TestParseKTX1(t *testing.T) {
+	level0, level1 := sequence(0, 2*2*4), sequence(100, 1*1*4)
+	img, err := parseKTX1(ktx1File(2, 2, level0, level1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.width != 2 || img.height != 2 || img.compressed || img.format != RGBA8 {
+		t.Fatalf("wrong image: %dx%d, format %v", img.width, img.height, img.format)
+	}
+	if len(img.levels) != 2 || !bytes.Equal(img.levels[0], level0) || !bytes.Equal(img.levels[1], level1) {
+		t.Fatalf("wrong levels: %v", img.levels)
+	}
+}
+
+func TestParseKTX1Invalid(t *testing.T) {
+	truncated := ktx1File(2, 2, sequence(0, 16))
+	tests := map[string][]byte{
+		"truncated header": truncated[:40],
+		"truncated level":  truncated[:len(truncated)-1],
+		"huge size":        ktx1File(0xFFFFFFFF, 0xFFFFFFFF, sequence(0, 16)),
+	}
+	for name, file := range tests {
+		if _, err := parseKTX1(file); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// ktx2File returns a KTX2 file of an RGBA8 texture with the given size and a single level stored
+// at the given offset.
+func ktx2File(width, height uint32, offset uint64, level []byte) []byte {
+	file := make([]byte, 80+24)
+	copy(file, ktx2Identifier)
+	le.PutUint32(file[12:], 37)
+	le.PutUint32(file[20:], width)
+	le.PutUint32(file[24:], height)
+	le.PutUint32(file[36:], 1)
+	le.PutUint32(file[40:], 1)
+	le.PutUint64(file[80:], offset)
+	le.PutUint64(file[88:], uint64(len(level)))
+	le.PutUint64(file[96:], uint64(len(level)))
+	return append(file, level...)
+}
+
+func TestParseKTX2(t *testing.T) {
+	level := sequence(0, 2*2*4)
+	img, err := parseKTX2(ktx2File(2, 2, 104, level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.width != 2 || img.height != 2 || img.compressed || img.format != RGBA8 {
+		t.Fatalf("wrong image: %dx%d, format %v", img.width, img.height, img.format)
+	}
+	if len(img.levels) != 1 || !bytes.Equal(img.levels[0], level) {
+		t.Fatalf("wrong levels: %v", img.levels)
+	}
+}
+
+func TestParseKTX2Invalid(t *testing.T) {
+	valid := ktx2File(2, 2, 104, sequence(0, 16))
+	tests := map[string][]byte{
+		"truncated header": valid[:60],
+		"truncated level":  valid[:len(valid)-1],
+		"overflow offset":  ktx2File(2, 2, 0xFFFFFFFFFFFFFFF8, sequence(0, 16)),
+		"huge size":        ktx2File(0xFFFFFFFF, 0xFFFFFFFF, 104, sequence(0, 16)),
+	}
+	for name, file := range tests {
+		if _, err := parseKTX2(file); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package glhf
+
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// Origin is the corner where the rows of pixel data start.
+type Origin int
+
+// List of all origins.
+const (
+	// BottomLeft is the OpenGL convention: the first row of pixel data is the bottom one. This is
+	// the default.
+	BottomLeft Origin = iota
+
+	// TopLeft is the screen and image file convention: the first row of pixel data is the top
+	// one, e.g. the Pix of an image.NRGBA can be uploaded as is.
+	TopLeft
+)
+
+// DepthRange is the range of depth in clip space that maps to the depth buffer.
+type DepthRange int
+
+// List of all depth ranges.
+const (
+	// NegativeOneToOne is the OpenGL convention: depth from -1 to 1. This is the default.
+	NegativeOneToOne DepthRange = iota
+
+	// ZeroToOne is the Direct3D and Vulkan convention: depth from 0 to 1. It has better precision
+	// with a reversed depth buffer. Requires OpenGL 4.5 or GL_ARB_clip_control.
+	ZeroToOne
+)
+
+// Convention is a package-wide coordinate system convention.
+type Convention struct {
+	Origin     Origin
+	DepthRange DepthRange
+}
+
+var convention Convention
+
+// SetConvention sets the package-wide coordinate system convention, so that it can be picked once
+// instead of flipping things ad hoc all around.
+//
+// The Origin applies to all pixel data exchanged with Textures and Frames: NewTexture, SetPixels,
+// Pixels, ReadTexture, ReadLayer, and so on. With TopLeft, the first row is the top one and the y
+// coordinates of sub-regions are measured from the top. The same goes for the rectangles of
+// Bounds, Viewport and BlitFrames, which are measured from the top of the Frame they apply to.
+// Since the size of the window isn't known to glhf, drawing into it with TopLeft requires going
+// through DefaultFrame. The DepthRange applies to the clip space of all draws.
+//
+// An error is returned if the convention isn't supported, in which case nothing changes.
+func SetConvention(c Convention) error {
+	if c.DepthRange == ZeroToOne && !versionAtLeast(4, 5) && !hasExtension("GL_ARB_clip_control") {
+		return errors.New("failed to set convention: zero to one depth range requires OpenGL 4.5 or GL_ARB_clip_control")
+	}
+	if c.DepthRange != convention.DepthRange {
+		depth := uint32(gl.NEGATIVE_ONE_TO_ONE)
+		if c.DepthRange == ZeroToOne {
+			depth = gl.ZERO_TO_ONE
+		}
+		gl.ClipControl(gl.LOWER_LEFT, depth)
+	}
+	convention = c
+	return nil
+}
+
+// CurrentConvention returns the package-wide coordinate system convention.
+func CurrentConvention() Convention {
+	return convention
+}
+
+// flipRows reverses the order of the rows of data in place, if the Origin is TopLeft, so that it
+// can be exchanged with OpenGL. The rows are rowLen elements long.
+func flipRows[T any](data []T, rowLen int) {
+	if convention.Origin != TopLeft || rowLen == 0 {
+		return
+	}
+	rows := len(data) / rowLen
+	tmp := make([]T, rowLen)
+	for i := 0; i < rows/2; i++ {
+		top := data[i*rowLen : (i+1)*rowLen]
+		bottom := data[(rows-1-i)*rowLen : (rows-i)*rowLen]
+		copy(tmp, top)
+		copy(top, bottom)
+		copy(bottom, tmp)
+	}
+}
+
+// frameHeights is the stack of the heights of the bound Frames, see Frame.Begin, so that the
+// rectangles of Bounds and Viewport can be converted between the Origin and OpenGL.
+var frameHeights []int
+
+// flipBoundsY is like flipY, except that the target is the currently bound Frame. Outside of any
+// Frame, the height of the target is unknown and y is returned as is.
+func flipBoundsY(y, h int) int {
+	if len(frameHeights) == 0 {
+		return y
+	}
+	return flipY(y, h, frameHeights[len(frameHeights)-1])
+}
+
+// flipCornerY converts the y coordinate of a corner of a rectangle within a target of the given
+// height between the Origin and OpenGL.
+func flipCornerY(y, height int) int {
+	return flipY(y, 0, height)
+}
+
+// flipY converts the y coordinate of a sub-region of height h within a target of the given height
+// between the Origin and OpenGL.
+func flipY(y, h, height int) int {
+	if convention.Origin != TopLeft {
+		return y
+	}
+	return height - y - h
+}
@@ -0,0 +1,16 @@
+package glhf
+
+var debug bool
+
+// SetDebug enables or disables the debug mode. In debug mode, glhf does extra checks which are
+// too expensive or too strict for production, such as detecting the use of objects after they
+// were deleted by Delete. Instead of silently corrupting the rendering, such use panics with a
+// description of the problem.
+func SetDebug(enabled bool) {
+	debug = enabled
+}
+
+// Debug returns whether the debug mode is enabled.
+func Debug() bool {
+	return debug
+}
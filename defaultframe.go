@@ -0,0 +1,44 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// DefaultFrame returns a Frame representing the default framebuffer (framebuffer 0), i.e. the
+// window, with the given size in pixels. This way, render code can target an offscreen Frame or
+// the window through the same interface: Begin and End (with the Bounds and the begin action),
+// Blit, BeginReadPixels, Image, and so on.
+//
+// The size must match the size of the framebuffer of the window, call Resize when it changes. The
+// default Frame has no Textures and no depth buffer can be attached to it, the window has its
+// own. Deleting it does nothing.
+func DefaultFrame(width, height int) *Frame {
+	f := &Frame{
+		fb: binder{
+			restoreLoc: gl.FRAMEBUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindFramebuffer(gl.FRAMEBUFFER, obj)
+			},
+		},
+		rf: binder{
+			restoreLoc: gl.READ_FRAMEBUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindFramebuffer(gl.READ_FRAMEBUFFER, obj)
+			},
+		},
+		df: binder{
+			restoreLoc: gl.DRAW_FRAMEBUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, obj)
+			},
+		},
+		width:      width,
+		height:     height,
+		isDefault:  true,
+		clearDepth: 1,
+	}
+	return f
+}
+
+// IsDefault returns whether the Frame represents the default framebuffer, see DefaultFrame.
+func (f *Frame) IsDefault() bool {
+	return f.isDefault
+}
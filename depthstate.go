@@ -0,0 +1,105 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// CompareFunc represents a comparison of a new value (e.g. the depth of a fragment) with the
+// stored one (e.g. the depth in the depth buffer). The new value passes if the comparison holds.
+type CompareFunc int
+
+// Here's the list of all compare functions.
+const (
+	CompareLess         = CompareFunc(gl.LESS)
+	CompareLessEqual    = CompareFunc(gl.LEQUAL)
+	CompareEqual        = CompareFunc(gl.EQUAL)
+	CompareGreater      = CompareFunc(gl.GREATER)
+	CompareGreaterEqual = CompareFunc(gl.GEQUAL)
+	CompareNotEqual     = CompareFunc(gl.NOTEQUAL)
+	CompareAlways       = CompareFunc(gl.ALWAYS)
+	CompareNever        = CompareFunc(gl.NEVER)
+)
+
+// DepthState describes the depth testing of drawn fragments against the depth buffer of the
+// current Frame (see Frame.SetDepthTexture) or the window.
+type DepthState struct {
+	// Test enables depth testing. Without it, fragments are always drawn and the depth buffer
+	// isn't written to, regardless of Write.
+	Test bool
+
+	// Func is the comparison of the depth of a fragment with the depth in the depth buffer, the
+	// fragment is drawn if it passes. The zero value means CompareLess.
+	Func CompareFunc
+
+	// Write enables writing the depths of drawn fragments into the depth buffer. It's usually
+	// disabled when drawing transparent geometry after the opaque one.
+	Write bool
+
+	// Near and Far map the normalized device depths to the window depths (glDepthRange), usually
+	// 0 and 1. Reversing them, or dividing the range between layers, are the common tricks. Both
+	// being zero means the default of 0 and 1, so that they can be left out.
+	Near, Far float64
+}
+
+// DefaultDepthState is the usual depth state of 3D rendering: testing and writing enabled, with
+// the closer fragments winning.
+var DefaultDepthState = DepthState{Test: true, Func: CompareLess, Write: true, Near: 0, Far: 1}
+
+// depthStates is the stack of depth states saved by PushDepthState.
+var depthStates []DepthState
+
+// SetDepthState sets the depth state. Depth testing is disabled by default.
+func SetDepthState(ds DepthState) {
+	if ds.Test {
+		gl.Enable(gl.DEPTH_TEST)
+	} else {
+		gl.Disable(gl.DEPTH_TEST)
+	}
+	depthFunc := uint32(ds.Func)
+	if ds.Func == 0 {
+		depthFunc = gl.LESS
+	}
+	gl.DepthFunc(depthFunc)
+	gl.DepthMask(ds.Write)
+	near, far := ds.Near, ds.Far
+	if near == 0 && far == 0 {
+		far = 1
+	}
+	gl.DepthRange(near, far)
+}
+
+// CurrentDepthState returns the current depth state, as queried from OpenGL, so it also reflects
+// changes made outside glhf.
+func CurrentDepthState() DepthState {
+	var (
+		ds        DepthState
+		depthFunc int32
+		depthRng  [2]float64
+	)
+	ds.Test = gl.IsEnabled(gl.DEPTH_TEST)
+	gl.GetIntegerv(gl.DEPTH_FUNC, &depthFunc)
+	ds.Func = CompareFunc(depthFunc)
+	gl.GetBooleanv(gl.DEPTH_WRITEMASK, &ds.Write)
+	gl.GetDoublev(gl.DEPTH_RANGE, &depthRng[0])
+	ds.Near, ds.Far = depthRng[0], depthRng[1]
+	return ds
+}
+
+// PushDepthState saves the current depth state and sets the new one. PopDepthState restores the
+// saved one. This way, a render pass can change the depth state without worrying about the one
+// the rest of the code expects, e.g.:
+//
+//   glhf.PushDepthState(glhf.DefaultDepthState)
+//   // draw 3D geometry
+//   glhf.PopDepthState()
+func PushDepthState(ds DepthState) {
+	depthStates = append(depthStates, CurrentDepthState())
+	SetDepthState(ds)
+}
+
+// PopDepthState restores the depth state saved by the last PushDepthState.
+func PopDepthState() {
+	if len(depthStates) == 0 {
+		panic("pop depth state: no depth state pushed")
+	}
+	SetDepthState(depthStates[len(depthStates)-1])
+	depthStates = depthStates[:len(depthStates)-1]
+}
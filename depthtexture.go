@@ -0,0 +1,301 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// DepthFormat is the format of the pixels of a DepthTexture.
+type DepthFormat int
+
+// List of all depth formats.
+const (
+	// Depth24 has a 24-bit normalized fixed point depth. It's the default.
+	Depth24 DepthFormat = iota
+
+	// Depth32F has a 32-bit floating point depth.
+	Depth32F
+
+	// Depth24Stencil8 has a 24-bit normalized fixed point depth and an 8-bit stencil. Attached to
+	// a Frame, it serves as both its depth and stencil buffer, which is necessary for masking and
+	// outlining techniques. Only the depth can be sampled.
+	Depth24Stencil8
+)
+
+// HasStencil returns whether the format has a stencil component.
+func (f DepthFormat) HasStencil() bool {
+	return f == Depth24Stencil8
+}
+
+func (f DepthFormat) internalFormat() int32 {
+	switch f {
+	case Depth24:
+		return gl.DEPTH_COMPONENT24
+	case Depth32F:
+		return gl.DEPTH_COMPONENT32F
+	case Depth24Stencil8:
+		return gl.DEPTH24_STENCIL8
+	}
+	panic("invalid depth format")
+}
+
+func (f DepthFormat) pixelFormat() uint32 {
+	if f.HasStencil() {
+		return gl.DEPTH_STENCIL
+	}
+	return gl.DEPTH_COMPONENT
+}
+
+// attachment returns the framebuffer attachment point of the format.
+func (f DepthFormat) attachment() uint32 {
+	if f.HasStencil() {
+		return gl.DEPTH_STENCIL_ATTACHMENT
+	}
+	return gl.DEPTH_ATTACHMENT
+}
+
+func (f DepthFormat) pixelType() uint32 {
+	switch f {
+	case Depth24:
+		return gl.UNSIGNED_INT
+	case Depth32F:
+		return gl.FLOAT
+	case Depth24Stencil8:
+		return gl.UNSIGNED_INT_24_8
+	}
+	panic("invalid depth format")
+}
+
+// DepthTexture is an OpenGL depth texture. It can be attached to a Frame as its depth buffer (see
+// Frame.SetDepthTexture) and then sampled, which is how shadow maps are made.
+//
+// Besides a plain 2D texture, it can be a texture array or a cubemap, see NewDepthTextureArray and
+// NewDepthCubemap, which are drawn into all layers at once by layered Frames.
+type DepthTexture struct {
+	tex           binder
+	target        uint32
+	width, height int
+	layers        int
+	format        DepthFormat
+	smooth        bool
+	compare       bool
+}
+
+// NewDepthTexture creates a new depth texture with the specified width, height and format. The
+// content of the texture is undefined until it's drawn into or cleared.
+func NewDepthTexture(width, height int, smooth bool, format DepthFormat) *DepthTexture {
+	return newDepthTexture(gl.TEXTURE_2D, gl.TEXTURE_BINDING_2D, width, height, 1, smooth, format)
+}
+
+// NewDepthTextureArray creates a new depth texture array with the specified width and height of
+// each layer, the number of layers and the format, e.g. for cascaded shadow maps. It's sampled
+// using a sampler2DArray, or a sampler2DArrayShadow with comparison enabled.
+func NewDepthTextureArray(width, height, layers int, smooth bool, format DepthFormat) *DepthTexture {
+	return newDepthTexture(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_BINDING_2D_ARRAY, width, height, layers, smooth, format)
+}
+
+// NewDepthCubemap creates a new depth cubemap with the specified size of each face and format,
+// e.g. for point light shadows. It has 6 layers, one per CubeFace. It's sampled using a
+// samplerCube, or a samplerCubeShadow with comparison enabled.
+func NewDepthCubemap(size int, smooth bool, format DepthFormat) *DepthTexture {
+	return newDepthTexture(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_BINDING_CUBE_MAP, size, size, 6, smooth, format)
+}
+
+func newDepthTexture(target, restoreLoc uint32, width, height, layers int, smooth bool, format DepthFormat) *DepthTexture {
+	dt := &DepthTexture{
+		tex: binder{
+			restoreLoc: restoreLoc,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(target, obj)
+			},
+		},
+		target: target,
+		width:  width,
+		height: height,
+		layers: layers,
+		format: format,
+	}
+
+	gl.GenTextures(1, &dt.tex.obj)
+
+	dt.Begin()
+	defer dt.End()
+
+	dt.allocate()
+
+	if target == gl.TEXTURE_CUBE_MAP {
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	} else {
+		// outside of a shadow map, everything is lit
+		borderColor := [4]float32{1, 1, 1, 1}
+		gl.TexParameterfv(target, gl.TEXTURE_BORDER_COLOR, &borderColor[0])
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+		gl.TexParameteri(target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	}
+
+	dt.SetSmooth(smooth)
+
+	runtime.SetFinalizer(dt, (*DepthTexture).delete)
+
+	return dt
+}
+
+// allocate (re)allocates the storage of the bound DepthTexture according to its size.
+func (dt *DepthTexture) allocate() {
+	switch dt.target {
+	case gl.TEXTURE_2D:
+		gl.TexImage2D(
+			gl.TEXTURE_2D,
+			0,
+			dt.format.internalFormat(),
+			int32(dt.width),
+			int32(dt.height),
+			0,
+			dt.format.pixelFormat(),
+			dt.format.pixelType(),
+			nil,
+		)
+	case gl.TEXTURE_2D_ARRAY:
+		gl.TexImage3D(
+			gl.TEXTURE_2D_ARRAY,
+			0,
+			dt.format.internalFormat(),
+			int32(dt.width),
+			int32(dt.height),
+			int32(dt.layers),
+			0,
+			dt.format.pixelFormat(),
+			dt.format.pixelType(),
+			nil,
+		)
+	case gl.TEXTURE_CUBE_MAP:
+		for face := uint32(0); face < 6; face++ {
+			gl.TexImage2D(
+				gl.TEXTURE_CUBE_MAP_POSITIVE_X+face,
+				0,
+				dt.format.internalFormat(),
+				int32(dt.width),
+				int32(dt.height),
+				0,
+				dt.format.pixelFormat(),
+				dt.format.pixelType(),
+				nil,
+			)
+		}
+	}
+}
+
+// Resize reallocates the storage of the DepthTexture with the new width and height, keeping its
+// OpenGL ID. The content is undefined afterwards.
+//
+// The DepthTexture must be bound before calling this method.
+func (dt *DepthTexture) Resize(width, height int) {
+	if dt.target == gl.TEXTURE_CUBE_MAP && width != height {
+		panic("resize: cubemap faces must be square")
+	}
+	dt.width, dt.height = width, height
+	dt.allocate()
+}
+
+func (dt *DepthTexture) delete() {
+	mainthread.CallNonBlock(dt.release)
+}
+
+// Delete deletes the DepthTexture immediately instead of waiting for the garbage collector. The
+// DepthTexture must not be used afterwards. Deleting a DepthTexture multiple times is harmless.
+func (dt *DepthTexture) Delete() {
+	runtime.SetFinalizer(dt, nil)
+	dt.release()
+}
+
+func (dt *DepthTexture) release() {
+	gl.DeleteTextures(1, &dt.tex.obj)
+	dt.tex.invalidate()
+}
+
+// ID returns the OpenGL ID of this DepthTexture.
+func (dt *DepthTexture) ID() uint32 {
+	return dt.tex.obj
+}
+
+// Width returns the width of the DepthTexture in pixels.
+func (dt *DepthTexture) Width() int {
+	return dt.width
+}
+
+// Height returns the height of the DepthTexture in pixels.
+func (dt *DepthTexture) Height() int {
+	return dt.height
+}
+
+// Layers returns the number of layers of the DepthTexture: 1 for a plain 2D one, 6 for a cubemap
+// and the number of array layers for an array.
+func (dt *DepthTexture) Layers() int {
+	return dt.layers
+}
+
+// Format returns the depth format of the DepthTexture.
+func (dt *DepthTexture) Format() DepthFormat {
+	return dt.format
+}
+
+// SetSmooth sets whether the DepthTexture is sampled "smoothly" or "pixely". With comparison
+// enabled, smooth sampling returns the average of the four nearest comparisons, which softens the
+// edges of shadows for free.
+//
+// The DepthTexture must be bound before calling this method.
+func (dt *DepthTexture) SetSmooth(smooth bool) {
+	dt.smooth = smooth
+	filter := int32(gl.NEAREST)
+	if smooth {
+		filter = gl.LINEAR
+	}
+	gl.TexParameteri(dt.target, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(dt.target, gl.TEXTURE_MAG_FILTER, filter)
+}
+
+// Smooth returns whether the DepthTexture is set to be sampled "smooth" or "pixely".
+func (dt *DepthTexture) Smooth() bool {
+	return dt.smooth
+}
+
+// SetCompare sets whether sampling the DepthTexture compares depths (GL_TEXTURE_COMPARE_MODE).
+//
+// Without comparison, the DepthTexture is sampled using a sampler2D and returns the depth. With
+// it, it must be sampled using a sampler2DShadow with a vec3 texture coordinate, the third
+// component being the reference depth, and returns 1 if the reference depth is less or equal to
+// the depth in the DepthTexture, 0 otherwise. For example:
+//
+//   uniform sampler2DShadow shadowMap;
+//   ...
+//   float lit = texture(shadowMap, vec3(lightPos.xy, lightPos.z - bias));
+//
+// The DepthTexture must be bound before calling this method.
+func (dt *DepthTexture) SetCompare(compare bool) {
+	dt.compare = compare
+	if compare {
+		gl.TexParameteri(dt.target, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+		gl.TexParameteri(dt.target, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+	} else {
+		gl.TexParameteri(dt.target, gl.TEXTURE_COMPARE_MODE, gl.NONE)
+	}
+}
+
+// Compare returns whether sampling the DepthTexture compares depths.
+func (dt *DepthTexture) Compare() bool {
+	return dt.compare
+}
+
+// Begin binds the DepthTexture. This is necessary before using the DepthTexture.
+func (dt *DepthTexture) Begin() {
+	dt.tex.bind()
+}
+
+// End unbinds the DepthTexture and restores the previous one.
+func (dt *DepthTexture) End() {
+	dt.tex.restore()
+}
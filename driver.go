@@ -0,0 +1,111 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// DriverInfo identifies the OpenGL driver of the current context.
+type DriverInfo struct {
+	Vendor                 string
+	Renderer               string
+	Version                string
+	ShadingLanguageVersion string
+}
+
+// Driver returns the information about the OpenGL driver of the current context. It's queried
+// by Init.
+func Driver() DriverInfo {
+	return driver
+}
+
+// Workaround is a known driver bug that glhf is able to avoid by taking an alternate code path.
+type Workaround int
+
+// List of all workarounds.
+const (
+	// AvoidMapBufferRange makes glhf update buffers using glBufferSubData instead of mapping
+	// them with glMapBufferRange, which is slow or broken on some drivers.
+	AvoidMapBufferRange Workaround = iota
+
+	// FinishAroundBlit makes glhf call glFinish before and after blitting framebuffers, which
+	// avoids corrupted blits on some drivers.
+	FinishAroundBlit
+
+	// AvoidBufferStorage makes glhf allocate vertex buffers using glBufferData even if immutable
+	// buffer storage (glBufferStorage) is supported. Persistent vertex slices are unaffected.
+	AvoidBufferStorage
+
+	numWorkarounds
+)
+
+func (w Workaround) String() string {
+	switch w {
+	case AvoidMapBufferRange:
+		return "AvoidMapBufferRange"
+	case FinishAroundBlit:
+		return "FinishAroundBlit"
+	case AvoidBufferStorage:
+		return "AvoidBufferStorage"
+	default:
+		return "Workaround(invalid)"
+	}
+}
+
+// RegisterWorkaround adds a rule, which enables the workaround w for all drivers for which the
+// match function returns true. The rules are evaluated by Init, so call this function before it.
+// There are no rules by default.
+func RegisterWorkaround(w Workaround, match func(DriverInfo) bool) {
+	workaroundRules = append(workaroundRules, workaroundRule{w, match})
+}
+
+// SetWorkaround manually enables or disables the workaround w, overriding the decision made by
+// Init.
+func SetWorkaround(w Workaround, enabled bool) {
+	workarounds[w] = enabled
+}
+
+// WorkaroundActive returns whether the workaround w is enabled.
+func WorkaroundActive(w Workaround) bool {
+	return workarounds[w]
+}
+
+// ActiveWorkarounds returns the list of all enabled workarounds.
+func ActiveWorkarounds() []Workaround {
+	var active []Workaround
+	for w := Workaround(0); w < numWorkarounds; w++ {
+		if workarounds[w] {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+var (
+	driver      DriverInfo
+	workarounds [numWorkarounds]bool
+)
+
+type workaroundRule struct {
+	w     Workaround
+	match func(DriverInfo) bool
+}
+
+// workaroundRules are the rules registered by RegisterWorkaround. None are built in, a rule is
+// only worth shipping with a reference to the driver bug and the affected versions.
+var workaroundRules []workaroundRule
+
+// detectDriver fingerprints the driver of the current context and enables the workarounds that
+// apply to it.
+func detectDriver() {
+	driver = DriverInfo{
+		Vendor:                 gl.GoStr(gl.GetString(gl.VENDOR)),
+		Renderer:               gl.GoStr(gl.GetString(gl.RENDERER)),
+		Version:                gl.GoStr(gl.GetString(gl.VERSION)),
+		ShadingLanguageVersion: gl.GoStr(gl.GetString(gl.SHADING_LANGUAGE_VERSION)),
+	}
+
+	workarounds = [numWorkarounds]bool{}
+	for _, rule := range workaroundRules {
+		if rule.match(driver) {
+			workarounds[rule.w] = true
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// TextureFormat is the format of the pixels of a Texture.
+type TextureFormat int
+
+// List of all texture formats.
+const (
+	// RGBA8 has four components (red, green, blue, alpha) with one byte each. It's the default.
+	RGBA8 TextureFormat = iota
+
+	// R8 has a single one byte component, e.g. for glyph coverage masks. It's sampled as red.
+	R8
+
+	// RG8 has two components (red, green) with one byte each.
+	RG8
+
+	// RGB8 has three components (red, green, blue) with one byte each.
+	RGB8
+
+	// R32F has a single 32-bit floating point component.
+	R32F
+
+	// RGBA32F has four 32-bit floating point components.
+	RGBA32F
+
+	// R32I has a single 32-bit signed integer component. It must be sampled using an isampler
+	// and the Texture must not be smooth.
+	R32I
+
+	// R32UI has a single 32-bit unsigned integer component. It must be sampled using a usampler
+	// and the Texture must not be smooth.
+	R32UI
+
+	// SRGBA8 is like RGBA8, except that the color components are sRGB encoded. They're decoded to
+	// linear when sampled, which is what colors from image files (e.g. albedo textures) need for
+	// gamma-correct lighting and blending. When drawn into, the colors are encoded back, if
+	// enabled by SetFramebufferSRGB.
+	SRGBA8
+
+	// RGBA16F has four 16-bit (half precision) floating point components. It's the usual format
+	// of HDR render targets. The pixels are exchanged as raw half floats, two bytes each.
+	RGBA16F
+
+	// R11FG11FB10F has three unsigned floating point components (red, green, blue) packed into 32
+	// bits. It's a compact format for HDR render targets that don't need alpha. The pixels are
+	// exchanged as packed 32-bit values (GL_UNSIGNED_INT_10F_11F_11F_REV).
+	R11FG11FB10F
+)
+
+// BytesPerPixel returns the number of bytes of a single pixel in the format.
+func (f TextureFormat) BytesPerPixel() int {
+	switch f {
+	case RGBA8, SRGBA8:
+		return 4
+	case R8:
+		return 1
+	case RG8:
+		return 2
+	case RGB8:
+		return 3
+	case R32F, R32I, R32UI, R11FG11FB10F:
+		return 4
+	case RGBA16F:
+		return 8
+	case RGBA32F:
+		return 16
+	}
+	panic("invalid texture format")
+}
+
+func (f TextureFormat) internalFormat() int32 {
+	switch f {
+	case RGBA8:
+		return gl.RGBA8
+	case R8:
+		return gl.R8
+	case RG8:
+		return gl.RG8
+	case RGB8:
+		return gl.RGB8
+	case R32F:
+		return gl.R32F
+	case RGBA32F:
+		return gl.RGBA32F
+	case R32I:
+		return gl.R32I
+	case R32UI:
+		return gl.R32UI
+	case SRGBA8:
+		return gl.SRGB8_ALPHA8
+	case RGBA16F:
+		return gl.RGBA16F
+	case R11FG11FB10F:
+		return gl.R11F_G11F_B10F
+	}
+	panic("invalid texture format")
+}
+
+func (f TextureFormat) pixelFormat() uint32 {
+	switch f {
+	case RGBA8, SRGBA8, RGBA16F:
+		return gl.RGBA
+	case R8:
+		return gl.RED
+	case RG8:
+		return gl.RG
+	case RGB8, R11FG11FB10F:
+		return gl.RGB
+	case R32F:
+		return gl.RED
+	case RGBA32F:
+		return gl.RGBA
+	case R32I, R32UI:
+		return gl.RED_INTEGER
+	}
+	panic("invalid texture format")
+}
+
+func (f TextureFormat) pixelType() uint32 {
+	switch f {
+	case R32F, RGBA32F:
+		return gl.FLOAT
+	case R32I:
+		return gl.INT
+	case R32UI:
+		return gl.UNSIGNED_INT
+	case RGBA16F:
+		return gl.HALF_FLOAT
+	case R11FG11FB10F:
+		return gl.UNSIGNED_INT_10F_11F_11F_REV
+	}
+	return gl.UNSIGNED_BYTE
+}
+
+// SetFramebufferSRGB sets whether drawing into an SRGBA8 texture (or into an sRGB capable window)
+// encodes the linear colors output by shaders to sRGB and blends in linear space. It's disabled
+// by default.
+func SetFramebufferSRGB(enabled bool) {
+	if enabled {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	} else {
+		gl.Disable(gl.FRAMEBUFFER_SRGB)
+	}
+}
+
+// pixelStore sets the alignment and the row length (in pixels, 0 means tightly packed rows) of
+// pixel uploads (pack == false) or downloads (pack == true) and returns a function that restores
+// the previous values.
+//
+// OpenGL assumes rows aligned to 4 bytes by default, which shears the pixels of any format and
+// width whose rows aren't a multiple of 4 bytes long, so glhf always uses the alignment of 1.
+func pixelStore(pack bool, alignment, rowLength int) (restore func()) {
+	alignmentLoc, rowLengthLoc := uint32(gl.UNPACK_ALIGNMENT), uint32(gl.UNPACK_ROW_LENGTH)
+	if pack {
+		alignmentLoc, rowLengthLoc = gl.PACK_ALIGNMENT, gl.PACK_ROW_LENGTH
+	}
+	var prevAlignment, prevRowLength int32
+	gl.GetIntegerv(alignmentLoc, &prevAlignment)
+	gl.GetIntegerv(rowLengthLoc, &prevRowLength)
+	gl.PixelStorei(alignmentLoc, int32(alignment))
+	gl.PixelStorei(rowLengthLoc, int32(rowLength))
+	return func() {
+		gl.PixelStorei(alignmentLoc, prevAlignment)
+		gl.PixelStorei(rowLengthLoc, prevRowLength)
+	}
+}
@@ -5,16 +5,129 @@ import (
 
 	"github.com/faiface/mainthread"
 	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
 )
 
 // Frame is a fixed resolution texture that you can draw on.
 type Frame struct {
 	fb, rf, df binder // framebuffer, read framebuffer, draw framebuffer
-	tex        *Texture
+	width      int
+	height     int
+	isDefault  bool       // whether it's the default framebuffer, see DefaultFrame
+	texs       []*Texture // color attachments
+	texGens    []uint32   // generations of texs when attached
+	depth      *DepthTexture
+	depthGen   uint32 // generation of depth when attached
+	depthRb    *Renderbuffer
+	ownDepthRb bool // whether depthRb is deleted together with the Frame
+
+	samples  int                   // 0 if not multisampled
+	msTexs   []*MultisampleTexture // color attachments if multisampled
+	colorRbs []*Renderbuffer       // color attachments if multisampled into Renderbuffers
+
+	layers     int           // 0 if not layered
+	layeredTex *TextureArray // color attachment if layered
+
+	manualBounds bool
+	prevBounds   [][2][4]int32 // viewport and scissor before each nested Begin
+
+	beginAction  BeginAction
+	clearColor   [4]float32
+	clearDepth   float64
+	clearStencil int32
 }
 
+// BeginAction specifies what happens to the contents of a Frame when it's bound by Begin.
+type BeginAction int
+
+const (
+	// LoadContents keeps the contents of the Frame. This is the default.
+	LoadContents BeginAction = iota
+
+	// ClearContents clears the Frame to the values set by SetClearColor and SetClearDepthStencil.
+	ClearContents
+
+	// DiscardContents tells the driver that the previous contents of the Frame are not needed
+	// anymore and may be left undefined. This saves memory bandwidth, especially on tile-based
+	// GPUs, when the whole Frame gets redrawn anyway.
+	//
+	// Discarding requires OpenGL 4.3 (or GL_ARB_invalidate_subdata). Otherwise, the contents
+	// are simply kept.
+	DiscardContents
+)
+
 // NewFrame creates a new fully transparent Frame with given dimensions in pixels.
 func NewFrame(width, height int, smooth bool) *Frame {
+	return NewFrameMRT(width, height, smooth, RGBA8)
+}
+
+// NewFrameFormat creates a new Frame with given dimensions in pixels, whose Texture has the
+// format. For example, RGBA16F or R11FG11FB10F for HDR rendering, or R32UI for an ID buffer used
+// for picking. The Frame is initially zero.
+func NewFrameFormat(width, height int, smooth bool, format TextureFormat) *Frame {
+	return NewFrameMRT(width, height, smooth, format)
+}
+
+// NewFrameMRT creates a new Frame with multiple render targets, i.e. with a color attachment for
+// each of the formats, which are all drawn into at once. The i-th output of a fragment shader
+// (layout(location = i) out) goes to the i-th attachment, e.g. the albedo, the normals and the
+// positions of a G-buffer. The attachments are initially zero.
+//
+// The Texture of each attachment can be accessed with ColorTexture.
+func NewFrameMRT(width, height int, smooth bool, formats ...TextureFormat) *Frame {
+	if len(formats) == 0 {
+		panic("failed to create frame: no color attachments")
+	}
+
+	f := newFrame(width, height)
+
+	f.fb.bind()
+	drawBuffers := make([]uint32, len(formats))
+	for i, format := range formats {
+		pixels := make([]uint8, width*height*format.BytesPerPixel())
+		tex := NewTextureFormat(width, height, smooth, format, pixels)
+		f.texs = append(f.texs, tex)
+		f.texGens = append(f.texGens, tex.tex.gen)
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, drawBuffers[i], gl.TEXTURE_2D, tex.tex.obj, 0)
+	}
+	gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	reportFramebuffer()
+	f.fb.restore()
+
+	runtime.SetFinalizer(f, (*Frame).delete)
+
+	return f
+}
+
+// NewDepthFrame creates a new Frame without any color attachments, which draws only into the
+// DepthTexture. This is the usual setup of shadow map passes and depth pre-passes, where the
+// fragment shader can be empty. The DepthTexture must be a plain 2D one, see NewLayeredDepthFrame
+// for texture arrays and cubemaps.
+//
+// The Frame doesn't own the DepthTexture, it isn't deleted together with the Frame.
+func NewDepthFrame(dt *DepthTexture) *Frame {
+	if dt.target != gl.TEXTURE_2D {
+		panic("failed to create depth frame: depth texture layered, use NewLayeredDepthFrame")
+	}
+
+	f := newFrame(dt.width, dt.height)
+
+	f.fb.bind()
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	f.depth, f.depthGen = dt, dt.tex.gen
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, dt.format.attachment(), gl.TEXTURE_2D, dt.tex.obj, 0)
+	reportFramebuffer()
+	f.fb.restore()
+
+	runtime.SetFinalizer(f, (*Frame).delete)
+
+	return f
+}
+
+// newFrame creates a Frame with a new framebuffer without any attachments.
+func newFrame(width, height int) *Frame {
 	f := &Frame{
 		fb: binder{
 			restoreLoc: gl.FRAMEBUFFER_BINDING,
@@ -34,24 +147,94 @@ func NewFrame(width, height int, smooth bool) *Frame {
 				gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, obj)
 			},
 		},
-		tex: NewTexture(width, height, smooth, make([]uint8, width*height*4)),
+		width:      width,
+		height:     height,
+		clearDepth: 1,
 	}
-
 	gl.GenFramebuffers(1, &f.fb.obj)
+	return f
+}
 
-	f.fb.bind()
-	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, f.tex.tex.obj, 0)
-	f.fb.restore()
-
-	runtime.SetFinalizer(f, (*Frame).delete)
+// checkFramebuffer returns an error if the currently bound framebuffer is incomplete, which makes
+// all draws silently do nothing.
+func checkFramebuffer() error {
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	if status == gl.FRAMEBUFFER_COMPLETE {
+		return nil
+	}
+	return errors.Errorf("failed to create frame: framebuffer incomplete (status 0x%x)", status)
+}
 
-	return f
+// reportFramebuffer checks the completeness of the currently bound framebuffer, see
+// checkFramebuffer, for functions that can't return an error. An incomplete one is reported
+// according to the failure policy: it panics with the Strict policy and it's logged with the
+// Resilient one.
+func reportFramebuffer() {
+	err := checkFramebuffer()
+	if err == nil {
+		return
+	}
+	if failurePolicy != Resilient {
+		panic(err)
+	}
+	logf("%v", err)
 }
 
 func (f *Frame) delete() {
-	mainthread.CallNonBlock(func() {
-		gl.DeleteFramebuffers(1, &f.fb.obj)
-	})
+	mainthread.CallNonBlock(f.release)
+}
+
+// Delete deletes the Frame immediately instead of waiting for the garbage collector, together
+// with its underlying Textures. Neither must be used afterwards. Deleting a Frame multiple times
+// is harmless.
+func (f *Frame) Delete() {
+	runtime.SetFinalizer(f, nil)
+	f.release()
+	for _, tex := range f.texs {
+		tex.Delete()
+	}
+	for _, mt := range f.msTexs {
+		mt.Delete()
+	}
+	for _, rb := range f.colorRbs {
+		rb.Delete()
+	}
+	if f.ownDepthRb {
+		f.depthRb.Delete()
+	}
+}
+
+func (f *Frame) release() {
+	if f.isDefault {
+		// the window owns the framebuffer
+		return
+	}
+	gl.DeleteFramebuffers(1, &f.fb.obj)
+	f.fb.invalidate()
+}
+
+// SetBeginAction sets what happens to the contents of the Frame each time it's bound by Begin.
+func (f *Frame) SetBeginAction(action BeginAction) {
+	f.beginAction = action
+}
+
+// BeginAction returns what happens to the contents of the Frame each time it's bound by Begin.
+func (f *Frame) BeginAction() BeginAction {
+	return f.beginAction
+}
+
+// SetClearColor sets the color that the Frame gets cleared to with the ClearContents begin action.
+// The default is fully transparent.
+func (f *Frame) SetClearColor(r, g, b, a float32) {
+	f.clearColor = [4]float32{r, g, b, a}
+}
+
+// SetClearDepthStencil sets the depth and stencil values that the Frame gets cleared to with the
+// ClearContents begin action. These only matter if the Frame has a depth or stencil buffer. The
+// defaults are 1 and 0.
+func (f *Frame) SetClearDepthStencil(depth float64, stencil int) {
+	f.clearDepth = depth
+	f.clearStencil = int32(stencil)
 }
 
 // ID returns the OpenGL framebuffer ID of this Frame.
@@ -59,18 +242,81 @@ func (f *Frame) ID() uint32 {
 	return f.fb.obj
 }
 
+// SetAutoBounds sets whether Begin should set the Bounds to cover the whole Frame and End should
+// restore the previous ones. This is enabled by default, so drawing into a Frame never uses the
+// viewport of some other, differently sized target by accident.
+//
+// Disable it to manage the Bounds manually.
+func (f *Frame) SetAutoBounds(auto bool) {
+	f.manualBounds = !auto
+}
+
+// AutoBounds returns whether Begin sets the Bounds to cover the whole Frame.
+func (f *Frame) AutoBounds() bool {
+	return !f.manualBounds
+}
+
 // Begin binds the Frame. All draw operations will target this Frame until End is called.
+//
+// Unless disabled by SetAutoBounds, the Bounds are set to cover the whole Frame. Then, depending
+// on the begin action of the Frame, its contents may also get cleared or discarded. Note, that
+// clearing is restricted by the current Bounds, just like drawing.
 func (f *Frame) Begin() {
+	for i, tex := range f.texs {
+		if debug && tex.tex.gen != f.texGens[i] {
+			panic("glhf: use of a frame whose texture was deleted or recreated")
+		}
+	}
+	if debug && f.depth != nil && f.depth.tex.gen != f.depthGen {
+		panic("glhf: use of a frame whose depth texture was deleted")
+	}
 	f.fb.bind()
+	frameHeights = append(frameHeights, f.height)
+
+	if !f.manualBounds {
+		var bounds [2][4]int32
+		gl.GetIntegerv(gl.VIEWPORT, &bounds[0][0])
+		gl.GetIntegerv(gl.SCISSOR_BOX, &bounds[1][0])
+		f.prevBounds = append(f.prevBounds, bounds)
+		Bounds(0, 0, f.width, f.height)
+	}
+
+	switch f.beginAction {
+	case ClearContents:
+		gl.ClearColor(f.clearColor[0], f.clearColor[1], f.clearColor[2], f.clearColor[3])
+		gl.ClearDepth(f.clearDepth)
+		gl.ClearStencil(f.clearStencil)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+	case DiscardContents:
+		if invalidateSupported() {
+			attachments := []uint32{gl.DEPTH_ATTACHMENT, gl.STENCIL_ATTACHMENT}
+			for i := 0; i < f.ColorAttachments(); i++ {
+				attachments = append(attachments, gl.COLOR_ATTACHMENT0+uint32(i))
+			}
+			if f.isDefault {
+				attachments = []uint32{gl.COLOR, gl.DEPTH, gl.STENCIL}
+			}
+			gl.InvalidateFramebuffer(gl.FRAMEBUFFER, int32(len(attachments)), &attachments[0])
+		}
+	}
 }
 
 // End unbinds the Frame. All draw operations will go to whatever was bound before this Frame.
+//
+// If Begin set the Bounds, the previous ones are restored.
 func (f *Frame) End() {
+	if len(f.prevBounds) > 0 {
+		bounds := f.prevBounds[len(f.prevBounds)-1]
+		f.prevBounds = f.prevBounds[:len(f.prevBounds)-1]
+		gl.Viewport(bounds[0][0], bounds[0][1], bounds[0][2], bounds[0][3])
+		gl.Scissor(bounds[1][0], bounds[1][1], bounds[1][2], bounds[1][3])
+	}
+	frameHeights = frameHeights[:len(frameHeights)-1]
 	f.fb.restore()
 }
 
 // Blit copies rectangle (sx0, sy0, sx1, sy1) in this Frame onto rectangle (dx0, dy0, dx1, dy1) in
-// dst Frame.
+// dst Frame. With multiple render targets, the first color attachments are copied.
 //
 // If the dst Frame is nil, the destination will be the framebuffer 0, which is the screen.
 //
@@ -78,18 +324,63 @@ func (f *Frame) End() {
 // rectangle. The stretch will be either smooth or pixely according to the source Frame's
 // smoothness.
 func (f *Frame) Blit(dst *Frame, sx0, sy0, sx1, sy1, dx0, dy0, dx1, dy1 int) {
-	f.rf.obj = f.fb.obj
+	filter := Nearest
+	if len(f.texs) > 0 && f.texs[0].smooth {
+		filter = Linear
+	}
+	BlitFrames(f, dst, sx0, sy0, sx1, sy1, dx0, dy0, dx1, dy1, filter)
+}
+
+// BlitFrames copies rectangle (sx0, sy0, sx1, sy1) in the src Frame onto rectangle (dx0, dy0, dx1,
+// dy1) in the dst Frame. A nil Frame stands for the framebuffer 0, which is the screen, so this
+// can present a post-processed Frame without drawing a textured quad, or grab the content of the
+// screen into a Frame. Only the first color attachment is copied, from src to the first color
+// attachment of dst.
+//
+// If the sizes of the rectangles don't match, the source will be stretched to fit the destination
+// rectangle using the filter, which must be Nearest or Linear.
+//
+// With the TopLeft origin (see SetConvention), the y coordinates are measured from the top of each
+// Frame. The height of the framebuffer 0 isn't known, so its rectangle is always measured from the
+// bottom, use DefaultFrame instead of nil to avoid that.
+func BlitFrames(src, dst *Frame, sx0, sy0, sx1, sy1, dx0, dy0, dx1, dy1 int, filter Filter) {
+	if filter != Nearest && filter != Linear {
+		panic("blit frames: invalid filter")
+	}
+
+	rf := binder{
+		restoreLoc: gl.READ_FRAMEBUFFER_BINDING,
+		bindFunc: func(obj uint32) {
+			gl.BindFramebuffer(gl.READ_FRAMEBUFFER, obj)
+		},
+	}
+	df := binder{
+		restoreLoc: gl.DRAW_FRAMEBUFFER_BINDING,
+		bindFunc: func(obj uint32) {
+			gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, obj)
+		},
+	}
+	if src != nil {
+		rf.obj = src.fb.obj
+		sy0, sy1 = flipCornerY(sy0, src.height), flipCornerY(sy1, src.height)
+	}
 	if dst != nil {
-		f.df.obj = dst.fb.obj
-	} else {
-		f.df.obj = 0
+		df.obj = dst.fb.obj
+		dy0, dy1 = flipCornerY(dy0, dst.height), flipCornerY(dy1, dst.height)
 	}
-	f.rf.bind()
-	f.df.bind()
+	rf.bind()
+	df.bind()
 
-	filter := gl.NEAREST
-	if f.tex.smooth {
-		filter = gl.LINEAR
+	// only the first color attachment of dst is the destination, like with drawing to a single
+	// render target
+	narrow := dst != nil && !dst.isDefault && dst.ColorAttachments() > 1
+	if narrow {
+		drawBuffer := uint32(gl.COLOR_ATTACHMENT0)
+		gl.DrawBuffers(1, &drawBuffer)
+	}
+
+	if WorkaroundActive(FinishAroundBlit) {
+		gl.Finish()
 	}
 
 	gl.BlitFramebuffer(
@@ -98,11 +389,234 @@ func (f *Frame) Blit(dst *Frame, sx0, sy0, sx1, sy1, dx0, dy0, dx1, dy1 int) {
 		gl.COLOR_BUFFER_BIT, uint32(filter),
 	)
 
-	f.rf.restore()
-	f.df.restore()
+	if WorkaroundActive(FinishAroundBlit) {
+		gl.Finish()
+	}
+
+	if narrow {
+		dst.resetDrawBuffers()
+	}
+
+	rf.restore()
+	df.restore()
 }
 
-// Texture returns the Frame's underlying Texture that the Frame draws on.
+// resetDrawBuffers sets the draw buffers of the Frame back to all of its color attachments. The
+// Frame must be bound as the draw framebuffer.
+func (f *Frame) resetDrawBuffers() {
+	drawBuffers := make([]uint32, f.ColorAttachments())
+	for i := range drawBuffers {
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+	}
+	gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+}
+
+// Resize reallocates all attachments of the Frame with the new width and height, including the
+// attached DepthTexture or Renderbuffer. The Frame and its attachments keep their OpenGL IDs, so
+// nothing referencing them needs to be updated, e.g. when the Frame is a canvas tied to a resizable
+// window.
+//
+// If preserve is true, the old content of the color attachments is copied over, anchored at the
+// origin (see SetConvention) and cropped to the new size, see Texture.Resize. The content of
+// multisampled and depth attachments is always undefined afterwards. Layered Frames can't be
+// resized.
+func (f *Frame) Resize(width, height int, preserve bool) {
+	if f.layers > 0 {
+		panic("resize: layered frame")
+	}
+	if f.isDefault {
+		// the window owns the framebuffer, just remember its new size
+		f.width, f.height = width, height
+		return
+	}
+	for _, tex := range f.texs {
+		tex.Begin()
+		tex.Resize(width, height, preserve)
+		tex.End()
+	}
+	for _, mt := range f.msTexs {
+		mt.Begin()
+		mt.Resize(width, height)
+		mt.End()
+	}
+	for _, rb := range f.colorRbs {
+		rb.Resize(width, height)
+	}
+	if f.depth != nil {
+		f.depth.Begin()
+		f.depth.Resize(width, height)
+		f.depth.End()
+	}
+	if f.depthRb != nil {
+		f.depthRb.Resize(width, height)
+	}
+	f.width, f.height = width, height
+
+	f.fb.bind()
+	reportFramebuffer()
+	f.fb.restore()
+}
+
+// Width returns the width of the Frame in pixels.
+func (f *Frame) Width() int {
+	return f.width
+}
+
+// Height returns the height of the Frame in pixels.
+func (f *Frame) Height() int {
+	return f.height
+}
+
+// Texture returns the Frame's underlying Texture that the Frame draws on. With multiple render
+// targets, it's the first color attachment. A multisampled Frame has no Texture, it must be
+// resolved into a regular one first.
 func (f *Frame) Texture() *Texture {
-	return f.tex
+	return f.ColorTexture(0)
+}
+
+// ColorFormat returns the format of the first color attachment of the Frame. It's RGBA8 for the
+// DefaultFrame.
+func (f *Frame) ColorFormat() TextureFormat {
+	switch {
+	case f.isDefault:
+		return RGBA8
+	case len(f.colorRbs) > 0:
+		return f.colorRbs[0].colorFormat
+	case f.samples > 0:
+		return f.msTexs[0].format
+	case f.layeredTex != nil:
+		return f.layeredTex.format
+	case len(f.texs) == 0:
+		panic("color format: frame without color attachments")
+	}
+	return f.texs[0].format
+}
+
+// ColorAttachments returns the number of color attachments of the Frame, which is 1 unless it was
+// created with multiple render targets, or 0 for a depth-only Frame (see NewDepthFrame).
+func (f *Frame) ColorAttachments() int {
+	switch {
+	case f.samples > 0:
+		return len(f.msTexs) + len(f.colorRbs)
+	case f.layeredTex != nil, f.isDefault:
+		return 1
+	}
+	return len(f.texs)
+}
+
+// ColorTexture returns the Texture of the i-th color attachment of the Frame.
+func (f *Frame) ColorTexture(i int) *Texture {
+	if f.samples > 0 {
+		panic("color texture: multisampled frame, use Resolve")
+	}
+	if f.layers > 0 {
+		panic("color texture: layered frame, use TextureArray")
+	}
+	if f.isDefault {
+		panic("color texture: default frame")
+	}
+	if len(f.texs) == 0 {
+		panic("color texture: depth-only frame")
+	}
+	return f.texs[i]
+}
+
+// SetDepthTexture attaches the DepthTexture to the Frame as its depth buffer, so that depth
+// testing works when drawing into the Frame and the depths can be sampled afterwards, e.g. as a
+// shadow map. If the format of the DepthTexture has a stencil, it's the stencil buffer of the
+// Frame as well. The DepthTexture must have the same size as the Frame and it must be layered with
+// the same number of layers if and only if the Frame is layered. Nil detaches the current one.
+//
+// The Frame doesn't own the DepthTexture, it isn't deleted together with the Frame.
+func (f *Frame) SetDepthTexture(dt *DepthTexture) {
+	if f.samples > 0 {
+		panic("set depth texture: multisampled frame")
+	}
+	if f.isDefault {
+		panic("set depth texture: default frame")
+	}
+	if dt != nil && (dt.width != f.width || dt.height != f.height) {
+		panic("set depth texture: size doesn't match the frame")
+	}
+	if dt != nil {
+		layered := dt.target != gl.TEXTURE_2D
+		if layered != (f.layers > 0) || layered && dt.layers != f.layers {
+			panic("set depth texture: layers don't match the frame")
+		}
+	}
+
+	f.fb.bind()
+	defer f.fb.restore()
+
+	f.detachDepth()
+	f.depth = dt
+	if dt == nil {
+		return
+	}
+	f.depthGen = dt.tex.gen
+	if f.layers > 0 {
+		gl.FramebufferTexture(gl.FRAMEBUFFER, dt.format.attachment(), dt.tex.obj, 0)
+	} else {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, dt.format.attachment(), gl.TEXTURE_2D, dt.tex.obj, 0)
+	}
+	reportFramebuffer()
+}
+
+// DepthTexture returns the DepthTexture attached to the Frame, or nil if there's none.
+func (f *Frame) DepthTexture() *DepthTexture {
+	return f.depth
+}
+
+// SetDepthRenderbuffer attaches the Renderbuffer to the Frame as its depth (and stencil) buffer,
+// replacing the current DepthTexture or Renderbuffer. Use it instead of a DepthTexture when the
+// depths never need to be sampled. The Renderbuffer must have the same size and number of samples
+// as the Frame. Nil detaches the current one.
+//
+// The Frame doesn't own the Renderbuffer, it isn't deleted together with the Frame.
+func (f *Frame) SetDepthRenderbuffer(rb *Renderbuffer) {
+	if rb != nil && (rb.width != f.width || rb.height != f.height) {
+		panic("set depth renderbuffer: size doesn't match the frame")
+	}
+	if rb != nil && rb.samples != f.samples {
+		panic("set depth renderbuffer: number of samples doesn't match the frame")
+	}
+	if rb != nil && rb.color {
+		panic("set depth renderbuffer: color renderbuffer")
+	}
+	if f.isDefault {
+		panic("set depth renderbuffer: default frame")
+	}
+
+	f.fb.bind()
+	defer f.fb.restore()
+
+	f.detachDepth()
+	f.depthRb = rb
+	if rb == nil {
+		return
+	}
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, rb.format.attachment(), gl.RENDERBUFFER, rb.rb.obj)
+	reportFramebuffer()
+}
+
+// DepthRenderbuffer returns the Renderbuffer attached to the Frame, or nil if there's none.
+func (f *Frame) DepthRenderbuffer() *Renderbuffer {
+	return f.depthRb
+}
+
+// detachDepth detaches the current DepthTexture or Renderbuffer from the bound framebuffer of the
+// Frame.
+func (f *Frame) detachDepth() {
+	switch {
+	case f.depth != nil:
+		gl.FramebufferTexture(gl.FRAMEBUFFER, f.depth.format.attachment(), 0, 0)
+		f.depth = nil
+	case f.depthRb != nil:
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, f.depthRb.format.attachment(), gl.RENDERBUFFER, 0)
+		if f.ownDepthRb {
+			f.depthRb.Delete()
+			f.ownDepthRb = false
+		}
+		f.depthRb = nil
+	}
 }
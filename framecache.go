@@ -0,0 +1,97 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// FrameCache renders expensive content, such as complex rich text or vector shapes, once into a
+// Frame and keeps it around, so that it can be blitted or drawn as a Texture every frame instead
+// of being rendered again. It trades video memory for per-frame CPU and GPU work.
+//
+// The cached Frames are keyed by a content hash supplied by the user (hash/fnv does the job). A
+// cached Frame is only rendered again when its key is invalidated or its size changes.
+type FrameCache struct {
+	smooth  bool
+	entries map[uint64]*frameCacheEntry
+}
+
+type frameCacheEntry struct {
+	frame *Frame
+	valid bool
+}
+
+// NewFrameCache creates a new empty FrameCache. The cached Frames are smooth or pixely according
+// to the smooth argument.
+func NewFrameCache(smooth bool) *FrameCache {
+	return &FrameCache{
+		smooth:  smooth,
+		entries: make(map[uint64]*frameCacheEntry),
+	}
+}
+
+// Frame returns the cached Frame with the given key. If there's no such Frame, it was
+// invalidated, or its size differs from width and height, the Frame is (re)rendered first: it's
+// cleared to fully transparent and the draw function is called with the Frame bound and the
+// Bounds set to cover the whole Frame. If the size changed, the previously returned Frame is
+// deleted and must not be used anymore.
+//
+// The current framebuffer, viewport and scissor are left intact.
+func (fc *FrameCache) Frame(key uint64, width, height int, draw func()) *Frame {
+	entry := fc.entries[key]
+	if entry != nil && entry.valid &&
+		entry.frame.Texture().Width() == width && entry.frame.Texture().Height() == height {
+		return entry.frame
+	}
+
+	if entry == nil || entry.frame.Texture().Width() != width || entry.frame.Texture().Height() != height {
+		if entry != nil {
+			entry.frame.Delete()
+		}
+		entry = &frameCacheEntry{frame: NewFrame(width, height, fc.smooth)}
+		fc.entries[key] = entry
+	}
+
+	var viewport, scissor [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	gl.GetIntegerv(gl.SCISSOR_BOX, &scissor[0])
+
+	entry.frame.Begin()
+	Bounds(0, 0, width, height)
+	Clear(0, 0, 0, 0)
+	draw()
+	entry.frame.End()
+
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+	gl.Scissor(scissor[0], scissor[1], scissor[2], scissor[3])
+
+	entry.valid = true
+	return entry.frame
+}
+
+// Invalidate marks the Frame with the given key to be rendered again the next time it's
+// requested. The Frame itself is kept and reused, if its size stays the same.
+func (fc *FrameCache) Invalidate(key uint64) {
+	if entry := fc.entries[key]; entry != nil {
+		entry.valid = false
+	}
+}
+
+// Remove removes the Frame with the given key from the FrameCache and deletes it, releasing its
+// memory. The removed Frame must not be used afterwards.
+func (fc *FrameCache) Remove(key uint64) {
+	if entry := fc.entries[key]; entry != nil {
+		entry.frame.Delete()
+		delete(fc.entries, key)
+	}
+}
+
+// Clear removes all Frames from the FrameCache and deletes them, see Remove.
+func (fc *FrameCache) Clear() {
+	for _, entry := range fc.entries {
+		entry.frame.Delete()
+	}
+	fc.entries = make(map[uint64]*frameCacheEntry)
+}
+
+// Len returns the number of Frames in the FrameCache.
+func (fc *FrameCache) Len() int {
+	return len(fc.entries)
+}
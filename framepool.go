@@ -0,0 +1,121 @@
+package glhf
+
+// FramePool caches Frames and hands them out as temporaries, e.g. for the intermediate results of
+// post-processing chains, so that complex effect stacks don't create new framebuffers every frame.
+//
+// Frames are requested by Get and recycled either individually by Put, or all at once by
+// EndFrame, which should be called at the end of each frame. Frames that stay unused for a whole
+// frame are deleted, so that the FramePool doesn't hoard memory when the sizes change, e.g. when
+// the window is resized.
+type FramePool struct {
+	smooth bool
+	free   map[framePoolKey][]framePoolEntry
+	used   map[*Frame]framePoolKey
+}
+
+type framePoolKey struct {
+	width, height int
+	format        TextureFormat
+	samples       int
+}
+
+type framePoolEntry struct {
+	frame *Frame
+	idle  int // number of EndFrame calls since the Frame was last used
+}
+
+// NewFramePool creates a new empty FramePool. The Frames are smooth or pixely according to the
+// smooth argument.
+func NewFramePool(smooth bool) *FramePool {
+	return &FramePool{
+		smooth: smooth,
+		free:   make(map[framePoolKey][]framePoolEntry),
+		used:   make(map[*Frame]framePoolKey),
+	}
+}
+
+// Get returns a Frame with the given size, format and number of samples per pixel (0 for a regular
+// Frame, see NewMultisampleFrame otherwise), reusing a recycled one if possible. The number of
+// samples must not be larger than MaxSamples.
+//
+// The content of the Frame is undefined and its settings (begin action, clear values, attached
+// depth buffer, ...) are as the previous user left them, so it's best to leave them alone or
+// restore them before recycling the Frame.
+func (p *FramePool) Get(width, height int, format TextureFormat, samples int) *Frame {
+	key := framePoolKey{width, height, format, samples}
+
+	var f *Frame
+	if entries := p.free[key]; len(entries) > 0 {
+		f = entries[len(entries)-1].frame
+		p.free[key] = entries[:len(entries)-1]
+	} else if samples > 0 {
+		var err error
+		f, err = NewMultisampleFrame(width, height, samples, false, format)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		f = NewFrameFormat(width, height, p.smooth, format)
+	}
+
+	p.used[f] = key
+	return f
+}
+
+// Put recycles the Frame before the end of the frame, so that it can be handed out again by Get
+// right away. The Frame must have been obtained by Get and must not be used afterwards.
+func (p *FramePool) Put(f *Frame) {
+	key, ok := p.used[f]
+	if !ok {
+		panic("put: frame not from this pool or already recycled")
+	}
+	delete(p.used, f)
+	p.free[key] = append(p.free[key], framePoolEntry{frame: f})
+}
+
+// EndFrame recycles all Frames handed out by Get since the last call to EndFrame and deletes the
+// ones that weren't used during the whole last frame. None of the handed out Frames must be used
+// afterwards.
+func (p *FramePool) EndFrame() {
+	for key, entries := range p.free {
+		kept := entries[:0]
+		for _, entry := range entries {
+			entry.idle++
+			if entry.idle > 1 {
+				entry.frame.Delete()
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		if len(kept) == 0 {
+			delete(p.free, key)
+		} else {
+			p.free[key] = kept
+		}
+	}
+	for f, key := range p.used {
+		p.free[key] = append(p.free[key], framePoolEntry{frame: f})
+		delete(p.used, f)
+	}
+}
+
+// Len returns the number of Frames in the FramePool, both handed out and recycled.
+func (p *FramePool) Len() int {
+	n := len(p.used)
+	for _, entries := range p.free {
+		n += len(entries)
+	}
+	return n
+}
+
+// Clear deletes all recycled Frames. The handed out ones are left alone, but they're forgotten by
+// the FramePool.
+func (p *FramePool) Clear() {
+	for _, entries := range p.free {
+		for _, entry := range entries {
+			entry.frame.Delete()
+		}
+	}
+	p.free = make(map[framePoolKey][]framePoolEntry)
+	p.used = make(map[*Frame]framePoolKey)
+}
@@ -1,6 +1,6 @@
 module github.com/faiface/glhf
 
-go 1.16
+go 1.18
 
 require (
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3
@@ -9,3 +9,5 @@ require (
 	github.com/go-gl/mathgl v1.0.0
 	github.com/pkg/errors v0.9.1
 )
+
+require golang.org/x/image v0.0.0-20190321063152-3fc05d484e9f // indirect
@@ -0,0 +1,121 @@
+package glhf
+
+import (
+	"image"
+	"image/draw"
+)
+
+// SetImage sets the content of a sub-region of the Texture to the image. The sub-region starts at
+// (x, y) and has the size of the image.
+//
+// The image is uploaded upright, i.e. its top row ends up at the top of the sub-region,
+// regardless of the convention (see SetConvention). The rows are converted internally, so any
+// stride and any image type work. *image.NRGBA is uploaded as is, other types are converted to
+// it first (non-premultiplied alpha). If the Texture has the R8 format, the image must be an
+// *image.Gray instead. Other formats are not supported.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetImage(x, y int, img image.Image) {
+	if t.compressed {
+		panic("set image: compressed texture")
+	}
+
+	var (
+		pix    []uint8
+		stride int
+		bpp    int
+	)
+	bounds := img.Bounds()
+	switch t.format {
+	case RGBA8, SRGBA8:
+		nrgba, ok := img.(*image.NRGBA)
+		if !ok {
+			nrgba = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+			draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+		}
+		pix, stride, bpp = nrgba.Pix[nrgba.PixOffset(nrgba.Rect.Min.X, nrgba.Rect.Min.Y):], nrgba.Stride, 4
+	case R8:
+		gray, ok := img.(*image.Gray)
+		if !ok {
+			panic("set image: texture format R8 requires an *image.Gray")
+		}
+		pix, stride, bpp = gray.Pix[gray.PixOffset(gray.Rect.Min.X, gray.Rect.Min.Y):], gray.Stride, 1
+	default:
+		panic("set image: unsupported texture format")
+	}
+
+	// tightly packed rows in the order of the convention, top-first for TopLeft
+	w, h := bounds.Dx(), bounds.Dy()
+	pixels := make([]uint8, w*h*bpp)
+	for i := 0; i < h; i++ {
+		row := i
+		if convention.Origin != TopLeft {
+			row = h - 1 - i
+		}
+		copy(pixels[row*w*bpp:(row+1)*w*bpp], pix[i*stride:i*stride+w*bpp])
+	}
+
+	t.setPixels(x, y, w, h, 0, pixels)
+}
+
+// Image returns the whole content of the Texture as an upright image, i.e. with the top row of
+// the Texture at the top, regardless of the convention (see SetConvention). The Texture must have
+// the RGBA8 or SRGBA8 format.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) Image() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, t.width, t.height))
+	t.ImageInto(img)
+	return img
+}
+
+// ImageInto is like Image, except that it writes the content of the Texture into an existing
+// image instead of allocating a new one, e.g. to reuse it for each screenshot. The image must have
+// the same size as the Texture.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) ImageInto(img *image.NRGBA) {
+	if t.format != RGBA8 && t.format != SRGBA8 {
+		panic("image: unsupported texture format")
+	}
+	if img.Rect.Dx() != t.width || img.Rect.Dy() != t.height {
+		panic("image: size doesn't match the texture")
+	}
+
+	pixels := t.Pixels(0, 0, t.width, t.height)
+	rowSize := t.width * 4
+	for i := 0; i < t.height; i++ {
+		row := i
+		if convention.Origin != TopLeft {
+			row = t.height - 1 - i
+		}
+		offset := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+i)
+		copy(img.Pix[offset:offset+rowSize], pixels[row*rowSize:(row+1)*rowSize])
+	}
+}
+
+// Image reads the rectangle of the first color attachment of the Frame into an upright image, i.e.
+// with the top row of the rectangle at the top, e.g. for screenshots and golden image tests. The
+// rectangle is in the coordinates of the convention (see SetConvention), so with the default
+// BottomLeft origin, its Min is the bottom-left corner. The returned image's bounds start at (0, 0).
+// The first color attachment must have the RGBA8 or SRGBA8 format, see ColorFormat.
+//
+// The pixels are read synchronously, which stalls until the GPU finishes drawing into the Frame.
+// See BeginReadPixels for an asynchronous alternative.
+func (f *Frame) Image(rect image.Rectangle) *image.NRGBA {
+	if format := f.ColorFormat(); format != RGBA8 && format != SRGBA8 {
+		panic("image: unsupported frame format")
+	}
+	pixels := f.BeginReadPixels(rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()).FinishReadPixels()
+
+	w, h := rect.Dx(), rect.Dy()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < h; i++ {
+		row := i
+		if convention.Origin != TopLeft {
+			row = h - 1 - i
+		}
+		copy(img.Pix[i*img.Stride:i*img.Stride+w*4], pixels[row*w*4:(row+1)*w*4])
+	}
+	return img
+}
@@ -0,0 +1,122 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// DispatchIndirectBuffer is a buffer of compute dispatch sizes. Each command in the buffer is
+// three unsigned integers: the number of work groups in X, Y and Z.
+//
+// The buffer can be written by a compute shader (it's bound as a shader storage buffer using
+// BindBase) and then used by Compute.DispatchIndirect. That way, one compute pass can decide the
+// amount of work for the next one without reading anything back to the CPU.
+type DispatchIndirectBuffer struct {
+	buf binder
+	len int
+}
+
+const dispatchIndirectCommandSize = 3 * 4
+
+// NewDispatchIndirectBuffer creates a new DispatchIndirectBuffer with room for len commands. All
+// commands are initially zero, i.e. they dispatch no work.
+func NewDispatchIndirectBuffer(len int) *DispatchIndirectBuffer {
+	b := &DispatchIndirectBuffer{
+		buf: binder{
+			restoreLoc: gl.DISPATCH_INDIRECT_BUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(gl.DISPATCH_INDIRECT_BUFFER, obj)
+			},
+		},
+		len: len,
+	}
+
+	gl.GenBuffers(1, &b.buf.obj)
+
+	b.Begin()
+	if len > 0 {
+		emptyData := make([]byte, len*dispatchIndirectCommandSize)
+		gl.BufferData(gl.DISPATCH_INDIRECT_BUFFER, len*dispatchIndirectCommandSize, gl.Ptr(emptyData), gl.DYNAMIC_DRAW)
+	} else {
+		// avoid setting 0 bytes of buffer data
+		gl.BufferData(gl.DISPATCH_INDIRECT_BUFFER, 0, nil, gl.DYNAMIC_DRAW)
+	}
+	b.End()
+
+	runtime.SetFinalizer(b, (*DispatchIndirectBuffer).delete)
+
+	return b
+}
+
+func (b *DispatchIndirectBuffer) delete() {
+	mainthread.CallNonBlock(b.release)
+}
+
+// Delete deletes the DispatchIndirectBuffer immediately instead of waiting for the garbage collector. The
+// DispatchIndirectBuffer must not be used afterwards. Deleting a DispatchIndirectBuffer multiple times is harmless.
+func (b *DispatchIndirectBuffer) Delete() {
+	runtime.SetFinalizer(b, nil)
+	b.release()
+}
+
+func (b *DispatchIndirectBuffer) release() {
+	gl.DeleteBuffers(1, &b.buf.obj)
+	b.buf.invalidate()
+}
+
+// ID returns the OpenGL ID of this DispatchIndirectBuffer.
+func (b *DispatchIndirectBuffer) ID() uint32 {
+	return b.buf.obj
+}
+
+// Len returns the number of commands in the DispatchIndirectBuffer.
+func (b *DispatchIndirectBuffer) Len() int {
+	return b.len
+}
+
+// SetCommand sets the i-th command of the DispatchIndirectBuffer from the CPU.
+//
+// The DispatchIndirectBuffer must be bound before calling this method.
+func (b *DispatchIndirectBuffer) SetCommand(i int, x, y, z int) {
+	if i < 0 || i >= b.len {
+		panic("set command: index out of range")
+	}
+	data := [3]uint32{uint32(x), uint32(y), uint32(z)}
+	gl.BufferSubData(gl.DISPATCH_INDIRECT_BUFFER, i*dispatchIndirectCommandSize, dispatchIndirectCommandSize, gl.Ptr(&data[0]))
+}
+
+// Command returns the i-th command of the DispatchIndirectBuffer. This reads the buffer back from
+// the GPU, so it stalls until all previous writes to it have finished.
+//
+// The DispatchIndirectBuffer must be bound before calling this method.
+func (b *DispatchIndirectBuffer) Command(i int) (x, y, z int) {
+	if i < 0 || i >= b.len {
+		panic("command: index out of range")
+	}
+	var data [3]uint32
+	gl.GetBufferSubData(gl.DISPATCH_INDIRECT_BUFFER, i*dispatchIndirectCommandSize, dispatchIndirectCommandSize, gl.Ptr(&data[0]))
+	return int(data[0]), int(data[1]), int(data[2])
+}
+
+// BindBase binds the DispatchIndirectBuffer to the shader storage buffer binding point index, so
+// that a compute shader can write the commands. The commands are tightly packed, so declare them
+// as an array of uints, where the i-th command occupies elements 3*i, 3*i+1 and 3*i+2:
+//   layout(std430, binding = 0) buffer Dispatch { uint commands[]; };
+//
+// Don't forget to call MemoryBarrier(CommandBarrier) after the compute shader wrote the commands
+// and before they are used by Compute.DispatchIndirect.
+func (b *DispatchIndirectBuffer) BindBase(index int) {
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, uint32(index), b.buf.obj)
+}
+
+// Begin binds the DispatchIndirectBuffer. This is necessary before using it.
+func (b *DispatchIndirectBuffer) Begin() {
+	b.buf.bind()
+}
+
+// End unbinds the DispatchIndirectBuffer and restores the previous one.
+func (b *DispatchIndirectBuffer) End() {
+	b.buf.restore()
+}
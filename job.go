@@ -0,0 +1,125 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// ComputeJob is a large compute dispatch split into tiles, which are executed gradually, a few
+// at a time, over many calls to Step. That way, heavy computations (image processing, baking,
+// etc.) don't freeze the application while they run: call Step once per frame and keep drawing.
+//
+// The compute shader receives the offset of the current tile in work groups through a uniform of
+// type IVec3, so it must add it to gl_WorkGroupID (or the corresponding multiple of the local size
+// to gl_GlobalInvocationID) to find out which part of the work to do.
+type ComputeJob struct {
+	compute       *Compute
+	offsetUniform int
+	groups, tile  [3]int
+	count         [3]int // number of tiles in each dimension
+
+	next, done int
+	chunk      int
+	fence      uintptr
+	progress   func(done, total int)
+}
+
+// NewComputeJob creates a ComputeJob, which dispatches x*y*z work groups of the Compute program
+// in tiles of tileX*tileY*tileZ work groups.
+//
+// The offsetUniform is the index of an IVec3 uniform in the uniform format of the Compute program,
+// which receives the offset of each tile in work groups.
+func NewComputeJob(compute *Compute, offsetUniform int, x, y, z, tileX, tileY, tileZ int) *ComputeJob {
+	if compute.uniformFmt[offsetUniform].Type != IVec3 {
+		panic("new compute job: offset uniform must be IVec3")
+	}
+	j := &ComputeJob{
+		compute:       compute,
+		offsetUniform: offsetUniform,
+		groups:        [3]int{x, y, z},
+		tile:          [3]int{tileX, tileY, tileZ},
+	}
+	for i := range j.count {
+		j.count[i] = (j.groups[i] + j.tile[i] - 1) / j.tile[i]
+	}
+
+	runtime.SetFinalizer(j, (*ComputeJob).delete)
+
+	return j
+}
+
+func (j *ComputeJob) delete() {
+	mainthread.CallNonBlock(func() {
+		if j.fence != 0 {
+			gl.DeleteSync(j.fence)
+		}
+	})
+}
+
+// SetProgress sets a function called each time a chunk of tiles finishes on the GPU. It receives
+// the number of finished tiles and the total number of tiles.
+func (j *ComputeJob) SetProgress(progress func(done, total int)) {
+	j.progress = progress
+}
+
+// Tiles returns the total number of tiles of the ComputeJob.
+func (j *ComputeJob) Tiles() int {
+	return j.count[0] * j.count[1] * j.count[2]
+}
+
+// Done returns whether all tiles of the ComputeJob have finished on the GPU.
+func (j *ComputeJob) Done() bool {
+	return j.done == j.Tiles()
+}
+
+// Step checks whether the previous chunk of tiles has finished on the GPU and if so, dispatches
+// the next chunk of at most maxTiles tiles. It never waits for the GPU, so if the previous chunk
+// is still running, Step does nothing.
+//
+// All resources used by the compute shader (storage buffers, images, etc.) must be bound before
+// calling this method. Step returns true once the whole ComputeJob is done.
+func (j *ComputeJob) Step(maxTiles int) (done bool) {
+	if j.fence != 0 {
+		status := gl.ClientWaitSync(j.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 0)
+		if status == gl.TIMEOUT_EXPIRED {
+			return false
+		}
+		gl.DeleteSync(j.fence)
+		j.fence = 0
+		j.done += j.chunk
+		j.chunk = 0
+		if j.progress != nil {
+			j.progress(j.done, j.Tiles())
+		}
+	}
+
+	if j.next == j.Tiles() {
+		return j.Done()
+	}
+
+	j.compute.Begin()
+	for ; j.chunk < maxTiles && j.next < j.Tiles(); j.chunk, j.next = j.chunk+1, j.next+1 {
+		tx := j.next % j.count[0]
+		ty := j.next / j.count[0] % j.count[1]
+		tz := j.next / j.count[0] / j.count[1]
+
+		var offset, size [3]int
+		for i, t := range [3]int{tx, ty, tz} {
+			offset[i] = t * j.tile[i]
+			size[i] = j.tile[i]
+			if offset[i]+size[i] > j.groups[i] {
+				size[i] = j.groups[i] - offset[i]
+			}
+		}
+
+		j.compute.SetUniformAttr(j.offsetUniform, [3]int32{int32(offset[0]), int32(offset[1]), int32(offset[2])})
+		j.compute.Dispatch(size[0], size[1], size[2])
+	}
+	j.compute.End()
+
+	j.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+
+	return false
+}
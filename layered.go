@@ -0,0 +1,65 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// NewLayeredFrame creates a new layered Frame, which draws into all layers of the TextureArray at
+// once. A geometry shader routes each primitive to a layer by writing gl_Layer, so e.g. all
+// cascades of a shadow map can be drawn in a single pass. A layered DepthTexture with the same
+// number of layers can be attached by SetDepthTexture.
+//
+// The Frame doesn't own the TextureArray, it isn't deleted together with the Frame.
+func NewLayeredFrame(ta *TextureArray) *Frame {
+	f := newFrame(ta.width, ta.height)
+	f.layers = ta.layers
+	f.layeredTex = ta
+
+	f.fb.bind()
+	gl.FramebufferTexture(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, ta.tex.obj, 0)
+	reportFramebuffer()
+	f.fb.restore()
+
+	runtime.SetFinalizer(f, (*Frame).delete)
+
+	return f
+}
+
+// NewLayeredDepthFrame creates a new layered Frame without any color attachments, which draws only
+// into all layers of the DepthTexture at once, see NewLayeredFrame. With a DepthTexture created by
+// NewDepthCubemap, this renders the shadow cubemap of a point light in a single pass, the geometry
+// shader emitting each primitive once per face.
+//
+// The Frame doesn't own the DepthTexture, it isn't deleted together with the Frame.
+func NewLayeredDepthFrame(dt *DepthTexture) *Frame {
+	if dt.target == gl.TEXTURE_2D {
+		panic("failed to create layered depth frame: depth texture not layered")
+	}
+
+	f := newFrame(dt.width, dt.height)
+	f.layers = dt.layers
+
+	f.fb.bind()
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	f.depth, f.depthGen = dt, dt.tex.gen
+	gl.FramebufferTexture(gl.FRAMEBUFFER, dt.format.attachment(), dt.tex.obj, 0)
+	reportFramebuffer()
+	f.fb.restore()
+
+	runtime.SetFinalizer(f, (*Frame).delete)
+
+	return f
+}
+
+// Layers returns the number of layers of the Frame, or 0 if it's not layered.
+func (f *Frame) Layers() int {
+	return f.layers
+}
+
+// TextureArray returns the TextureArray a layered Frame draws into, or nil if there's none.
+func (f *Frame) TextureArray() *TextureArray {
+	return f.layeredTex
+}
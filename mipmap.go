@@ -0,0 +1,260 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// MipFilter is a downsampling filter used when generating mipmaps with a compute shader.
+type MipFilter int
+
+// List of all mipmap downsampling filters.
+const (
+	// BoxMipFilter averages each 2x2 block of texels. It's what glGenerateMipmap usually does.
+	BoxMipFilter MipFilter = iota
+
+	// KaiserMipFilter is a Kaiser-windowed sinc filter. It keeps the mipmaps sharper than the box
+	// filter with almost no ringing.
+	KaiserMipFilter
+
+	// LanczosMipFilter is a 3-lobe Lanczos filter. It's the sharpest of the filters, but it
+	// may cause slight ringing around high-contrast edges.
+	LanczosMipFilter
+)
+
+// GenerateMipmapsCompute allocates the full mipmap chain of the Texture and fills it by
+// downsampling each level into the next one with a compute shader using the given filter.
+//
+// If alphaWeighted is true, color channels are weighted by alpha when downsampling. This stops
+// fully transparent texels (which often have black color) from darkening the edges of
+// alpha-cutout sprites in lower mipmap levels.
+//
+// Once generated, the mipmaps are used for minification (see SetSmooth).
+//
+// This method requires compute shaders, if they are not supported, ErrComputeUnavailable is
+// returned and the Texture is left untouched. Only RGBA8 Textures with mutable storage are
+// supported. Like GenerateMipmaps, it resets the maximum mipmap level (see SetLevelRange).
+func (t *Texture) GenerateMipmapsCompute(filter MipFilter, alphaWeighted bool) error {
+	if !ComputeSupported() {
+		return ErrComputeUnavailable
+	}
+	if t.format != RGBA8 {
+		return errors.New("failed to generate mipmaps: texture format not RGBA8")
+	}
+
+	t.Begin()
+	defer t.End()
+
+	if t.immutable() {
+		return errors.New("failed to generate mipmaps: immutable texture")
+	}
+	if mipmapCompute == nil {
+		// not NewComputeProgram, the placeholder of the Resilient policy would do nothing
+		var err error
+		mipmapCompute, err = newComputeProgram(mipmapUniformFormat, mipmapComputeShader)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate mipmaps")
+		}
+	}
+
+	// allocate all levels first, so that the texture is complete while generating
+	width, height := t.width, t.height
+	levels := 1
+	for width > 1 || height > 1 {
+		width, height = mipSize(width), mipSize(height)
+		gl.TexImage2D(
+			gl.TEXTURE_2D,
+			int32(levels),
+			gl.RGBA,
+			int32(width),
+			int32(height),
+			0,
+			gl.RGBA,
+			gl.UNSIGNED_BYTE,
+			nil,
+		)
+		levels++
+	}
+	// the source levels are fetched by the shader, so they must be within the level range
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(levels-1))
+
+	var activeTexture int32
+	gl.GetIntegerv(gl.ACTIVE_TEXTURE, &activeTexture)
+
+	unit, err := AcquireBinding(ImageUnit)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate mipmaps")
+	}
+	defer ReleaseBinding(ImageUnit, unit)
+
+	mipmapCompute.Begin()
+	defer mipmapCompute.End()
+
+	alpha := int32(0)
+	if alphaWeighted {
+		alpha = 1
+	}
+	mipmapCompute.SetUniformAttr(mipmapUniformSrc, activeTexture-gl.TEXTURE0)
+	mipmapCompute.SetUniformAttr(mipmapUniformFilter, int32(filter))
+	mipmapCompute.SetUniformAttr(mipmapUniformAlphaWeighted, alpha)
+	mipmapCompute.SetUniformAttr(mipmapUniformDst, int32(unit))
+
+	width, height = t.width, t.height
+	for level := 1; level < levels; level++ {
+		width, height = mipSize(width), mipSize(height)
+		mipmapCompute.SetUniformAttr(mipmapUniformSrcLevel, int32(level-1))
+		gl.BindImageTexture(uint32(unit), t.tex.obj, int32(level), false, 0, gl.WRITE_ONLY, gl.RGBA8)
+		mipmapCompute.Dispatch((width+7)/8, (height+7)/8, 1)
+		MemoryBarrier(TextureFetchBarrier)
+	}
+	MemoryBarrier(TextureUpdateBarrier | FramebufferBarrier)
+
+	t.mipmapped = true
+	t.SetSmooth(t.smooth)
+
+	return nil
+}
+
+// mipSize returns the size of the next mipmap level given the size of the current one.
+func mipSize(size int) int {
+	if size/2 < 1 {
+		return 1
+	}
+	return size / 2
+}
+
+// mipmapCompute is compiled lazily on the first call to GenerateMipmapsCompute in each context.
+var mipmapCompute *Compute
+
+// forgetMipmapCompute forgets the mipmapCompute of the previous context, so that it's compiled
+// again in the current one. It's called by Init and InitCompute.
+func forgetMipmapCompute() {
+	if mipmapCompute != nil {
+		// the program belongs to the previous context, deleting it in this one would delete
+		// an unrelated program
+		runtime.SetFinalizer(mipmapCompute, nil)
+		mipmapCompute = nil
+	}
+}
+
+const (
+	mipmapUniformSrc = iota
+	mipmapUniformSrcLevel
+	mipmapUniformFilter
+	mipmapUniformAlphaWeighted
+	mipmapUniformDst
+)
+
+var mipmapUniformFormat = AttrFormat{
+	mipmapUniformSrc:           {Name: "src", Type: Int},
+	mipmapUniformSrcLevel:      {Name: "srcLevel", Type: Int},
+	mipmapUniformFilter:        {Name: "mipFilter", Type: Int},
+	mipmapUniformAlphaWeighted: {Name: "alphaWeighted", Type: Int},
+	mipmapUniformDst:           {Name: "dst", Type: Int},
+}
+
+const mipmapComputeShader = `
+#version 430 core
+
+layout(local_size_x = 8, local_size_y = 8) in;
+
+uniform sampler2D src;
+uniform int srcLevel;
+uniform int mipFilter;
+uniform int alphaWeighted;
+
+layout(rgba8) uniform writeonly image2D dst;
+
+const float PI = 3.14159265;
+
+float sinc(float x) {
+	if (abs(x) < 1e-5) {
+		return 1.0;
+	}
+	x *= PI;
+	return sin(x) / x;
+}
+
+float besselI0(float x) {
+	float sum = 1.0;
+	float term = 1.0;
+	for (int k = 1; k < 16; k++) {
+		float t = x / (2.0 * float(k));
+		term *= t * t;
+		sum += term;
+	}
+	return sum;
+}
+
+// support returns the radius of the filter in destination texels
+float support() {
+	if (mipFilter == 1) {
+		return 2.0;
+	}
+	if (mipFilter == 2) {
+		return 3.0;
+	}
+	return 0.5;
+}
+
+// weight returns the filter weight at the distance x (in destination texels)
+float weight(float x) {
+	float radius = support();
+	if (abs(x) >= radius) {
+		return 0.0;
+	}
+	if (mipFilter == 1) {
+		const float beta = 4.0;
+		float r = x / radius;
+		return sinc(x) * besselI0(beta * sqrt(1.0 - r*r)) / besselI0(beta);
+	}
+	if (mipFilter == 2) {
+		return sinc(x) * sinc(x / radius);
+	}
+	return 1.0;
+}
+
+void main() {
+	ivec2 dstSize = imageSize(dst);
+	ivec2 d = ivec2(gl_GlobalInvocationID.xy);
+	if (d.x >= dstSize.x || d.y >= dstSize.y) {
+		return;
+	}
+
+	ivec2 srcSize = textureSize(src, srcLevel);
+	vec2 scale = vec2(srcSize) / vec2(dstSize);
+	vec2 center = (vec2(d) + 0.5) * scale;
+	ivec2 lo = ivec2(floor(center - support()*scale));
+	ivec2 hi = ivec2(ceil(center + support()*scale));
+
+	vec4 sum = vec4(0.0);
+	float alphaSum = 0.0;
+	float weightSum = 0.0;
+	for (int y = lo.y; y < hi.y; y++) {
+		for (int x = lo.x; x < hi.x; x++) {
+			vec2 p = (vec2(x, y) + 0.5 - center) / scale;
+			float w = weight(p.x) * weight(p.y);
+			if (w == 0.0) {
+				continue;
+			}
+			vec4 c = texelFetch(src, clamp(ivec2(x, y), ivec2(0), srcSize-1), srcLevel);
+			if (alphaWeighted != 0) {
+				sum.rgb += w * c.a * c.rgb;
+				alphaSum += w * c.a;
+			} else {
+				sum.rgb += w * c.rgb;
+			}
+			sum.a += w * c.a;
+			weightSum += w;
+		}
+	}
+
+	vec4 result = sum / weightSum;
+	if (alphaWeighted != 0 && alphaSum != 0.0) {
+		result.rgb = sum.rgb / alphaSum;
+	}
+	imageStore(dst, d, clamp(result, 0.0, 1.0));
+}
+`
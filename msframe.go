@@ -0,0 +1,190 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// NewMultisampleFrame creates a new multisampled Frame, which antialiases everything drawn into
+// it, with given dimensions in pixels, the number of samples per pixel and a color attachment for
+// each of the formats (see NewFrameMRT). If depthStencil is true, the Frame also gets a
+// multisampled Depth24Stencil8 Renderbuffer, which is deleted together with the Frame. Otherwise,
+// one can be attached by SetDepthRenderbuffer.
+//
+// A multisampled Frame can't be sampled directly, resolve it into a regular Frame of the same
+// size first, see Resolve. Its color attachments are MultisampleTextures, so they can also be
+// resolved by a custom shader. An error is returned if the number of samples is not supported, or
+// if the combination of attachments isn't (unless the failure policy is Resilient, which only logs
+// it).
+func NewMultisampleFrame(width, height, samples int, depthStencil bool, formats ...TextureFormat) (*Frame, error) {
+	return newMultisampleFrame(width, height, samples, depthStencil, false, formats)
+}
+
+// NewMultisampleRenderbufferFrame is like NewMultisampleFrame, except that the color attachments
+// are color Renderbuffers instead of MultisampleTextures (see NewColorRenderbuffer). They can't be
+// resolved by a custom shader, only by Resolve, but they're cheaper on many drivers.
+func NewMultisampleRenderbufferFrame(width, height, samples int, depthStencil bool, formats ...TextureFormat) (*Frame, error) {
+	return newMultisampleFrame(width, height, samples, depthStencil, true, formats)
+}
+
+// newMultisampleFrame creates a new multisampled Frame, whose color attachments are Renderbuffers
+// if renderbuffers is true, or MultisampleTextures otherwise.
+func newMultisampleFrame(width, height, samples int, depthStencil, renderbuffers bool, formats []TextureFormat) (*Frame, error) {
+	if len(formats) == 0 {
+		panic("failed to create multisample frame: no color attachments")
+	}
+	if samples < 1 {
+		return nil, errors.Errorf("failed to create multisample frame: %d samples not supported", samples)
+	}
+
+	var depthRb *Renderbuffer
+	if depthStencil {
+		var err error
+		depthRb, err = NewRenderbuffer(width, height, samples, Depth24Stencil8)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create multisample frame")
+		}
+	}
+
+	var (
+		msTexs   []*MultisampleTexture
+		colorRbs []*Renderbuffer
+	)
+	for _, format := range formats {
+		var err error
+		if renderbuffers {
+			var rb *Renderbuffer
+			if rb, err = NewColorRenderbuffer(width, height, samples, format); err == nil {
+				colorRbs = append(colorRbs, rb)
+			}
+		} else {
+			var mt *MultisampleTexture
+			if mt, err = NewMultisampleTexture(width, height, samples, format); err == nil {
+				msTexs = append(msTexs, mt)
+			}
+		}
+		if err != nil {
+			for _, mt := range msTexs {
+				mt.Delete()
+			}
+			for _, rb := range colorRbs {
+				rb.Delete()
+			}
+			if depthRb != nil {
+				depthRb.Delete()
+			}
+			return nil, errors.Wrap(err, "failed to create multisample frame")
+		}
+	}
+
+	f := newFrame(width, height)
+	f.samples = samples
+	f.msTexs = msTexs
+	f.colorRbs = colorRbs
+
+	f.fb.bind()
+	drawBuffers := make([]uint32, len(formats))
+	for i := range drawBuffers {
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		if renderbuffers {
+			gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, drawBuffers[i], gl.RENDERBUFFER, colorRbs[i].rb.obj)
+		} else {
+			gl.FramebufferTexture2D(gl.FRAMEBUFFER, drawBuffers[i], gl.TEXTURE_2D_MULTISAMPLE, msTexs[i].tex.obj, 0)
+		}
+	}
+	gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	if depthRb != nil {
+		f.depthRb, f.ownDepthRb = depthRb, true
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, depthRb.format.attachment(), gl.RENDERBUFFER, depthRb.rb.obj)
+	}
+	err := checkFramebuffer()
+	f.fb.restore()
+	if err != nil && failurePolicy != Resilient {
+		f.Delete()
+		return nil, err
+	}
+	if err != nil {
+		logf("%v", err)
+	}
+
+	runtime.SetFinalizer(f, (*Frame).delete)
+
+	return f, nil
+}
+
+// Samples returns the number of samples per pixel of the Frame, or 0 if it's not multisampled.
+func (f *Frame) Samples() int {
+	return f.samples
+}
+
+// MultisampleTexture returns the MultisampleTexture of the i-th color attachment of a
+// multisampled Frame.
+func (f *Frame) MultisampleTexture(i int) *MultisampleTexture {
+	if f.samples == 0 {
+		panic("multisample texture: frame not multisampled")
+	}
+	if f.colorRbs != nil {
+		panic("multisample texture: frame with renderbuffers, use ColorRenderbuffer")
+	}
+	return f.msTexs[i]
+}
+
+// ColorRenderbuffer returns the Renderbuffer of the i-th color attachment of a Frame created by
+// NewMultisampleRenderbufferFrame.
+func (f *Frame) ColorRenderbuffer(i int) *Renderbuffer {
+	if f.colorRbs == nil {
+		panic("color renderbuffer: frame without color renderbuffers")
+	}
+	return f.colorRbs[i]
+}
+
+// Resolve averages the samples of each pixel of the multisampled Frame into the dst Frame, which
+// must be a regular Frame of the same size. Each color attachment is resolved into the color
+// attachment of dst with the same index, as long as dst has it.
+//
+// If the dst Frame is nil, the destination will be the framebuffer 0, which is the screen.
+func (f *Frame) Resolve(dst *Frame) {
+	if f.samples == 0 {
+		panic("resolve: frame not multisampled")
+	}
+	if dst != nil && (dst.width != f.width || dst.height != f.height) {
+		panic("resolve: size of dst doesn't match the frame")
+	}
+
+	f.rf.obj = f.fb.obj
+	f.df.obj = 0
+	n := 1
+	if dst != nil {
+		f.df.obj = dst.fb.obj
+		n = f.ColorAttachments()
+		if dst.ColorAttachments() < n {
+			n = dst.ColorAttachments()
+		}
+	}
+	f.rf.bind()
+	f.df.bind()
+
+	for i := 0; i < n; i++ {
+		gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + uint32(i))
+		if dst != nil && !dst.isDefault {
+			drawBuffer := gl.COLOR_ATTACHMENT0 + uint32(i)
+			gl.DrawBuffers(1, &drawBuffer)
+		}
+		gl.BlitFramebuffer(
+			0, 0, int32(f.width), int32(f.height),
+			0, 0, int32(f.width), int32(f.height),
+			gl.COLOR_BUFFER_BIT, gl.NEAREST,
+		)
+	}
+
+	// restore the read and draw buffers, they're part of the framebuffer state
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	if dst != nil && !dst.isDefault && n > 0 {
+		dst.resetDrawBuffers()
+	}
+
+	f.rf.restore()
+	f.df.restore()
+}
@@ -0,0 +1,184 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// DrawIndirectBuffer is a buffer of draw commands for VertexSlice.MultiDrawIndirect. Each command
+// in the buffer is a DrawArraysIndirectCommand.
+//
+// The buffer can be written by a compute shader (it's bound as a shader storage buffer using
+// BindBase), so that a GPU-driven renderer can cull and emit thousands of draws without any
+// involvement of the CPU.
+type DrawIndirectBuffer struct {
+	buf binder
+	len int
+}
+
+// DrawArraysIndirectCommand is a single draw command of a DrawIndirectBuffer, laid out exactly as
+// the GPU expects it.
+type DrawArraysIndirectCommand struct {
+	Count         uint32 // number of vertices
+	InstanceCount uint32 // number of instances
+	First         uint32 // first vertex
+	BaseInstance  uint32 // first instance
+}
+
+// DrawElementsIndirectCommand is a single indexed draw command, laid out exactly as the GPU
+// expects it. Use it to fill a raw Buffer with DrawIndirectTarget for indexed drawing.
+type DrawElementsIndirectCommand struct {
+	Count         uint32 // number of indices
+	InstanceCount uint32 // number of instances
+	FirstIndex    uint32 // first index
+	BaseVertex    int32  // added to each index
+	BaseInstance  uint32 // first instance
+}
+
+// GLSL declarations of the indirect command structs. Paste them into a compute shader that
+// generates draw commands, e.g.
+//   layout(std430, binding = 0) buffer Draws { DrawArraysIndirectCommand commands[]; };
+const (
+	DrawArraysIndirectCommandGLSL = `struct DrawArraysIndirectCommand {
+	uint count;
+	uint instanceCount;
+	uint first;
+	uint baseInstance;
+};
+`
+	DrawElementsIndirectCommandGLSL = `struct DrawElementsIndirectCommand {
+	uint count;
+	uint instanceCount;
+	uint firstIndex;
+	int baseVertex;
+	uint baseInstance;
+};
+`
+)
+
+// Encode appends the command in the binary layout expected by the GPU to buf and returns the
+// extended slice.
+func (c DrawArraysIndirectCommand) Encode(buf []byte) []byte {
+	for _, v := range [...]uint32{c.Count, c.InstanceCount, c.First, c.BaseInstance} {
+		buf = appendUint32(buf, v)
+	}
+	return buf
+}
+
+// Encode appends the command in the binary layout expected by the GPU to buf and returns the
+// extended slice.
+func (c DrawElementsIndirectCommand) Encode(buf []byte) []byte {
+	for _, v := range [...]uint32{c.Count, c.InstanceCount, c.FirstIndex, uint32(c.BaseVertex), c.BaseInstance} {
+		buf = appendUint32(buf, v)
+	}
+	return buf
+}
+
+// appendUint32 appends v in the native byte order of the GPU, which is little endian on all
+// supported platforms.
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+const drawIndirectCommandSize = 4 * 4
+
+// NewDrawIndirectBuffer creates a new DrawIndirectBuffer with room for len commands. All commands are
+// initially zero, i.e. they draw nothing.
+func NewDrawIndirectBuffer(len int) *DrawIndirectBuffer {
+	b := &DrawIndirectBuffer{
+		buf: binder{
+			restoreLoc: gl.DRAW_INDIRECT_BUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(gl.DRAW_INDIRECT_BUFFER, obj)
+			},
+		},
+		len: len,
+	}
+
+	gl.GenBuffers(1, &b.buf.obj)
+
+	b.Begin()
+	if len > 0 {
+		emptyData := make([]byte, len*drawIndirectCommandSize)
+		gl.BufferData(gl.DRAW_INDIRECT_BUFFER, len*drawIndirectCommandSize, gl.Ptr(emptyData), gl.DYNAMIC_DRAW)
+	} else {
+		// avoid setting 0 bytes of buffer data
+		gl.BufferData(gl.DRAW_INDIRECT_BUFFER, 0, nil, gl.DYNAMIC_DRAW)
+	}
+	b.End()
+
+	runtime.SetFinalizer(b, (*DrawIndirectBuffer).delete)
+
+	return b
+}
+
+func (b *DrawIndirectBuffer) delete() {
+	mainthread.CallNonBlock(b.release)
+}
+
+// Delete deletes the DrawIndirectBuffer immediately instead of waiting for the garbage collector. The
+// DrawIndirectBuffer must not be used afterwards. Deleting a DrawIndirectBuffer multiple times is harmless.
+func (b *DrawIndirectBuffer) Delete() {
+	runtime.SetFinalizer(b, nil)
+	b.release()
+}
+
+func (b *DrawIndirectBuffer) release() {
+	gl.DeleteBuffers(1, &b.buf.obj)
+	b.buf.invalidate()
+}
+
+// ID returns the OpenGL ID of this DrawIndirectBuffer.
+func (b *DrawIndirectBuffer) ID() uint32 {
+	return b.buf.obj
+}
+
+// Len returns the number of commands in the DrawIndirectBuffer.
+func (b *DrawIndirectBuffer) Len() int {
+	return b.len
+}
+
+// SetCommand sets the i-th command of the DrawIndirectBuffer from the CPU.
+//
+// The DrawIndirectBuffer must be bound before calling this method.
+func (b *DrawIndirectBuffer) SetCommand(i int, cmd DrawArraysIndirectCommand) {
+	if i < 0 || i >= b.len {
+		panic("set command: index out of range")
+	}
+	data := cmd.Encode(make([]byte, 0, drawIndirectCommandSize))
+	gl.BufferSubData(gl.DRAW_INDIRECT_BUFFER, i*drawIndirectCommandSize, drawIndirectCommandSize, gl.Ptr(data))
+}
+
+// Command returns the i-th command of the DrawIndirectBuffer. This reads the buffer back from the
+// GPU, so it stalls until all previous writes to it have finished.
+//
+// The DrawIndirectBuffer must be bound before calling this method.
+func (b *DrawIndirectBuffer) Command(i int) DrawArraysIndirectCommand {
+	if i < 0 || i >= b.len {
+		panic("command: index out of range")
+	}
+	var data [4]uint32
+	gl.GetBufferSubData(gl.DRAW_INDIRECT_BUFFER, i*drawIndirectCommandSize, drawIndirectCommandSize, gl.Ptr(&data[0]))
+	return DrawArraysIndirectCommand{data[0], data[1], data[2], data[3]}
+}
+
+// BindBase binds the DrawIndirectBuffer to the shader storage buffer binding point index, so
+// that a compute shader can write the commands. Declare them using DrawArraysIndirectCommandGLSL.
+//
+// Don't forget to call MemoryBarrier(CommandBarrier) after the compute shader wrote the commands
+// and before they are used by VertexSlice.MultiDrawIndirect.
+func (b *DrawIndirectBuffer) BindBase(index int) {
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, uint32(index), b.buf.obj)
+}
+
+// Begin binds the DrawIndirectBuffer. This is necessary before using it.
+func (b *DrawIndirectBuffer) Begin() {
+	b.buf.bind()
+}
+
+// End unbinds the DrawIndirectBuffer and restores the previous one.
+func (b *DrawIndirectBuffer) End() {
+	b.buf.restore()
+}
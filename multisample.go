@@ -0,0 +1,146 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// MultisampleTexture is an OpenGL multisampled texture, which stores several samples per pixel.
+//
+// It can't be filtered, a shader reads the individual samples from a sampler2DMS uniform using
+// texelFetch, which makes custom resolves (e.g. tone mapping each sample before averaging them)
+// and per-sample shading possible. For example:
+//
+//   uniform sampler2DMS scene;
+//   ...
+//   vec4 color = texelFetch(scene, ivec2(gl_FragCoord.xy), sampleIndex);
+type MultisampleTexture struct {
+	tex           binder
+	width, height int
+	samples       int
+	format        TextureFormat
+}
+
+// MaxSamples returns the maximum number of samples per pixel of a MultisampleTexture supported by
+// the current OpenGL context.
+//
+// Init must be called before this function.
+func MaxSamples() int {
+	var samples int32
+	gl.GetIntegerv(gl.MAX_SAMPLES, &samples)
+	return int(samples)
+}
+
+// NewMultisampleTexture creates a new multisampled texture with the specified width, height,
+// number of samples per pixel and pixel format. The content of the texture is undefined until
+// it's drawn into or cleared.
+//
+// The samples are in the same positions in all pixels, so that resolving them is consistent
+// across the texture. An error is returned if the number of samples is larger than MaxSamples.
+func NewMultisampleTexture(width, height, samples int, format TextureFormat) (*MultisampleTexture, error) {
+	if samples < 1 || samples > MaxSamples() {
+		return nil, errors.Errorf("failed to create multisample texture: %d samples not supported", samples)
+	}
+	if err := checkTextureSize(width, height); err != nil {
+		return nil, errors.Wrap(err, "failed to create multisample texture")
+	}
+
+	mt := &MultisampleTexture{
+		tex: binder{
+			restoreLoc: gl.TEXTURE_BINDING_2D_MULTISAMPLE,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(gl.TEXTURE_2D_MULTISAMPLE, obj)
+			},
+		},
+		width:   width,
+		height:  height,
+		samples: samples,
+		format:  format,
+	}
+
+	gl.GenTextures(1, &mt.tex.obj)
+
+	mt.Begin()
+	mt.allocate()
+	mt.End()
+
+	runtime.SetFinalizer(mt, (*MultisampleTexture).delete)
+
+	return mt, nil
+}
+
+// allocate (re)allocates the storage of the bound MultisampleTexture according to its size.
+func (mt *MultisampleTexture) allocate() {
+	gl.TexImage2DMultisample(
+		gl.TEXTURE_2D_MULTISAMPLE,
+		int32(mt.samples),
+		uint32(mt.format.internalFormat()),
+		int32(mt.width),
+		int32(mt.height),
+		true,
+	)
+}
+
+// Resize reallocates the storage of the MultisampleTexture with the new width and height, keeping
+// its OpenGL ID. The content is undefined afterwards.
+//
+// The MultisampleTexture must be bound before calling this method.
+func (mt *MultisampleTexture) Resize(width, height int) {
+	mt.width, mt.height = width, height
+	mt.allocate()
+}
+
+func (mt *MultisampleTexture) delete() {
+	mainthread.CallNonBlock(mt.release)
+}
+
+// Delete deletes the MultisampleTexture immediately instead of waiting for the garbage
+// collector. The MultisampleTexture must not be used afterwards. Deleting a MultisampleTexture
+// multiple times is harmless.
+func (mt *MultisampleTexture) Delete() {
+	runtime.SetFinalizer(mt, nil)
+	mt.release()
+}
+
+func (mt *MultisampleTexture) release() {
+	gl.DeleteTextures(1, &mt.tex.obj)
+	mt.tex.invalidate()
+}
+
+// ID returns the OpenGL ID of this MultisampleTexture.
+func (mt *MultisampleTexture) ID() uint32 {
+	return mt.tex.obj
+}
+
+// Width returns the width of the MultisampleTexture in pixels.
+func (mt *MultisampleTexture) Width() int {
+	return mt.width
+}
+
+// Height returns the height of the MultisampleTexture in pixels.
+func (mt *MultisampleTexture) Height() int {
+	return mt.height
+}
+
+// Samples returns the number of samples per pixel of the MultisampleTexture.
+func (mt *MultisampleTexture) Samples() int {
+	return mt.samples
+}
+
+// Format returns the pixel format of the MultisampleTexture.
+func (mt *MultisampleTexture) Format() TextureFormat {
+	return mt.format
+}
+
+// Begin binds the MultisampleTexture. This is necessary before using the MultisampleTexture.
+func (mt *MultisampleTexture) Begin() {
+	mt.tex.bind()
+}
+
+// End unbinds the MultisampleTexture and restores the previous one.
+func (mt *MultisampleTexture) End() {
+	mt.tex.restore()
+}
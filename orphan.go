@@ -1,6 +1,9 @@
 package glhf
 
-import "github.com/go-gl/gl/v3.3-core/gl"
+import (
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
 
 // Init initializes OpenGL by loading function pointers from the active OpenGL context.
 // This function must be manually run inside the main thread (using "github.com/faiface/mainthread"
@@ -8,30 +11,138 @@ import "github.com/go-gl/gl/v3.3-core/gl"
 //
 // It must be called under the presence of an active OpenGL context, e.g., always after calling
 // window.MakeContextCurrent(). Also, always call this function when switching contexts.
+//
+// Init also queries the capabilities of the context, such as whether it supports compute
+// shaders (see ComputeSupported), and identifies the driver to enable the workarounds registered
+// for it (see RegisterWorkaround).
 func Init() {
 	err := gl.Init()
 	if err != nil {
 		panic(err)
 	}
+	queryCaps()
+	detectDriver()
+	forgetMipmapCompute()
 	gl.Enable(gl.BLEND)
 	gl.Enable(gl.SCISSOR_TEST)
 	gl.BlendEquation(gl.FUNC_ADD)
 }
 
+// InitCompute initializes OpenGL for using glhf only for general purpose computations on the GPU,
+// i.e. compute shaders, shader storage buffers and image load/store, without drawing anything.
+// Call it instead of Init.
+//
+// The same rules as with Init apply, the only difference is that the active context doesn't have
+// to have a default framebuffer, so it may be a headless one. InitCompute doesn't set up any
+// rendering state and instead of panicking, it returns an error if OpenGL failed to initialize or
+// if the context lacks any of the compute features.
+func InitCompute() error {
+	err := gl.Init()
+	if err != nil {
+		return err
+	}
+	queryCaps()
+	detectDriver()
+	forgetMipmapCompute()
+
+	if !ComputeSupported() {
+		return ErrComputeUnavailable
+	}
+	if !versionAtLeast(4, 3) && !hasExtension("GL_ARB_shader_storage_buffer_object") {
+		return errors.New("shader storage buffers require OpenGL 4.3 or the GL_ARB_shader_storage_buffer_object extension")
+	}
+	if !versionAtLeast(4, 2) && !hasExtension("GL_ARB_shader_image_load_store") {
+		return errors.New("image load/store requires OpenGL 4.2 or the GL_ARB_shader_image_load_store extension")
+	}
+	return nil
+}
+
 // Clear clears the current framebuffer or window with the given color.
 func Clear(r, g, b, a float32) {
 	gl.ClearColor(r, g, b, a)
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 }
 
+// ClearDepthStencil clears the depth and stencil buffers of the current framebuffer or window with
+// the given values, e.g. 1 and 0. Like Clear, it's restricted by the current Bounds.
+func ClearDepthStencil(depth float64, stencil int) {
+	gl.ClearDepth(depth)
+	gl.ClearStencil(int32(stencil))
+	gl.Clear(gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+}
+
 // Bounds sets the drawing bounds in pixels. Drawing outside bounds is always discarted.
 //
-// Calling this function is equivalent to setting viewport and scissor in OpenGL.
+// Calling this function is equivalent to setting viewport and scissor in OpenGL. With the TopLeft
+// origin (see SetConvention), y is measured from the top of the current Frame.
 func Bounds(x, y, w, h int) {
+	y = flipBoundsY(y, h)
 	gl.Viewport(int32(x), int32(y), int32(w), int32(h))
 	gl.Scissor(int32(x), int32(y), int32(w), int32(h))
 }
 
+// Viewport sets only the viewport in pixels, i.e. the rectangle the normalized device
+// coordinates are mapped to, without restricting drawing to it like Bounds does.
+//
+// Frame.Begin sets the Bounds to cover the whole Frame and End restores the previous ones (see
+// Frame.SetAutoBounds), so Viewport is only needed for drawing into a part of a Frame, e.g. split
+// screen views or tiles of an atlas. With the TopLeft origin (see SetConvention), y is measured
+// from the top of the current Frame.
+func Viewport(x, y, w, h int) {
+	y = flipBoundsY(y, h)
+	gl.Viewport(int32(x), int32(y), int32(w), int32(h))
+}
+
+// viewports is the stack of viewports saved by PushViewport.
+var viewports [][4]int32
+
+// PushViewport saves the current viewport and sets the new one, see Viewport. PopViewport restores
+// the saved one.
+func PushViewport(x, y, w, h int) {
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	viewports = append(viewports, viewport)
+	Viewport(x, y, w, h)
+}
+
+// PopViewport restores the viewport saved by the last PushViewport.
+func PopViewport() {
+	if len(viewports) == 0 {
+		panic("pop viewport: no viewport pushed")
+	}
+	viewport := viewports[len(viewports)-1]
+	viewports = viewports[:len(viewports)-1]
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+}
+
+// DrawArrays draws count vertices as the specified kind of primitives using the Shader, without
+// any vertex buffer. The shader gets no vertex attributes, so it has to compute everything from
+// gl_VertexID (and gl_InstanceID). This is useful for full-screen triangles and procedural
+// geometry, which would otherwise need a dummy VertexSlice.
+//
+// The Shader is bound for the duration of the draw.
+func DrawArrays(shader *Shader, primitive Primitive, count int) {
+	if emptyVao.obj == 0 {
+		// core profile can't draw without a vertex array object, even an empty one
+		emptyVao = binder{
+			restoreLoc: gl.VERTEX_ARRAY_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindVertexArray(obj)
+			},
+		}
+		gl.GenVertexArrays(1, &emptyVao.obj)
+	}
+
+	shader.Begin()
+	emptyVao.bind()
+	gl.DrawArrays(primitive.glMode(), 0, int32(count))
+	emptyVao.restore()
+	shader.End()
+}
+
+// emptyVao is a vertex array object without any attributes, used by DrawArrays.
+var emptyVao binder
+
 // BlendFactor represents a source or destination blend factor.
 type BlendFactor int
 
@@ -43,9 +154,50 @@ const (
 	DstAlpha         = BlendFactor(gl.DST_ALPHA)
 	OneMinusSrcAlpha = BlendFactor(gl.ONE_MINUS_SRC_ALPHA)
 	OneMinusDstAlpha = BlendFactor(gl.ONE_MINUS_DST_ALPHA)
+	SrcColor         = BlendFactor(gl.SRC_COLOR)
+	DstColor         = BlendFactor(gl.DST_COLOR)
+	OneMinusSrcColor = BlendFactor(gl.ONE_MINUS_SRC_COLOR)
+	OneMinusDstColor = BlendFactor(gl.ONE_MINUS_DST_COLOR)
+
+	// ConstantColor and OneMinusConstantColor use the color set by SetBlendColor.
+	ConstantColor         = BlendFactor(gl.CONSTANT_COLOR)
+	OneMinusConstantColor = BlendFactor(gl.ONE_MINUS_CONSTANT_COLOR)
 )
 
-// BlendFunc sets the source and destination blend factor.
+// BlendFunc sets the source and destination blend factor. See SetBlend for more control.
 func BlendFunc(src, dst BlendFactor) {
 	gl.BlendFunc(uint32(src), uint32(dst))
 }
+
+// BarrierBit represents a kind of memory access ordered by MemoryBarrier. Multiple bits can be
+// combined using the bitwise OR operator.
+type BarrierBit uint32
+
+// Here's the list of all barrier bits.
+const (
+	VertexAttribArrayBarrier = BarrierBit(gl.VERTEX_ATTRIB_ARRAY_BARRIER_BIT)
+	ElementArrayBarrier      = BarrierBit(gl.ELEMENT_ARRAY_BARRIER_BIT)
+	UniformBarrier           = BarrierBit(gl.UNIFORM_BARRIER_BIT)
+	TextureFetchBarrier      = BarrierBit(gl.TEXTURE_FETCH_BARRIER_BIT)
+	ImageAccessBarrier       = BarrierBit(gl.SHADER_IMAGE_ACCESS_BARRIER_BIT)
+	CommandBarrier           = BarrierBit(gl.COMMAND_BARRIER_BIT)
+	PixelBufferBarrier       = BarrierBit(gl.PIXEL_BUFFER_BARRIER_BIT)
+	TextureUpdateBarrier     = BarrierBit(gl.TEXTURE_UPDATE_BARRIER_BIT)
+	BufferUpdateBarrier      = BarrierBit(gl.BUFFER_UPDATE_BARRIER_BIT)
+	FramebufferBarrier       = BarrierBit(gl.FRAMEBUFFER_BARRIER_BIT)
+	TransformFeedbackBarrier = BarrierBit(gl.TRANSFORM_FEEDBACK_BARRIER_BIT)
+	AtomicCounterBarrier     = BarrierBit(gl.ATOMIC_COUNTER_BARRIER_BIT)
+	ShaderStorageBarrier     = BarrierBit(gl.SHADER_STORAGE_BARRIER_BIT)
+	AllBarriers              = BarrierBit(gl.ALL_BARRIER_BITS)
+)
+
+// MemoryBarrier makes sure that the data written by shaders (e.g. into shader storage buffers or
+// images) before this call is visible to the operations specified by bits issued after it.
+//
+// For example, call MemoryBarrier(VertexAttribArrayBarrier) after a compute shader wrote vertex
+// data and before drawing it.
+//
+// This function requires OpenGL 4.2 or the ARB_shader_image_load_store extension.
+func MemoryBarrier(bits BarrierBit) {
+	gl.MemoryBarrier(uint32(bits))
+}
@@ -0,0 +1,111 @@
+package glhf
+
+import "math"
+
+// The Pack functions pack values of compact vertex attribute types into float32 elements of
+// vertex data (see VertexSlice.SetVertexData). The resulting float32 is not a meaningful number,
+// it only carries the bits of the packed values.
+//
+// Attribute types occupying 8 bytes (UShort4Norm, Short4Norm, Half4) take two elements, pack the
+// first two components into the first one and the other two into the second one.
+//
+// The packing assumes a little-endian machine, which all common platforms are.
+
+// PackUByte4Norm packs four values in range [0, 1] for an attribute of type UByte4Norm, e.g. an
+// RGBA color. Values outside the range are clamped.
+func PackUByte4Norm(x, y, z, w float32) float32 {
+	return math.Float32frombits(
+		unorm(x, 0xff) |
+			unorm(y, 0xff)<<8 |
+			unorm(z, 0xff)<<16 |
+			unorm(w, 0xff)<<24,
+	)
+}
+
+// PackByte4Norm packs four values in range [-1, 1] for an attribute of type Byte4Norm, e.g. a
+// normal. Values outside the range are clamped.
+func PackByte4Norm(x, y, z, w float32) float32 {
+	return math.Float32frombits(
+		uint32(uint8(snorm(x, 0x7f))) |
+			uint32(uint8(snorm(y, 0x7f)))<<8 |
+			uint32(uint8(snorm(z, 0x7f)))<<16 |
+			uint32(uint8(snorm(w, 0x7f)))<<24,
+	)
+}
+
+// PackUShort2Norm packs two values in range [0, 1] for an attribute of type UShort2Norm (or half
+// of UShort4Norm), e.g. texture coordinates. Values outside the range are clamped.
+func PackUShort2Norm(x, y float32) float32 {
+	return math.Float32frombits(unorm(x, 0xffff) | unorm(y, 0xffff)<<16)
+}
+
+// PackShort2Norm packs two values in range [-1, 1] for an attribute of type Short2Norm (or half of
+// Short4Norm). Values outside the range are clamped.
+func PackShort2Norm(x, y float32) float32 {
+	return math.Float32frombits(uint32(uint16(snorm(x, 0x7fff))) | uint32(uint16(snorm(y, 0x7fff)))<<16)
+}
+
+// PackHalf2 packs two values as half-precision floats for an attribute of type Half2 (or half of
+// Half4).
+func PackHalf2(x, y float32) float32 {
+	return math.Float32frombits(uint32(halfFloat(x)) | uint32(halfFloat(y))<<16)
+}
+
+func unorm(v float32, max float32) uint32 {
+	if v < 0 {
+		v = 0
+	}
+	if v > 1 {
+		v = 1
+	}
+	return uint32(v*max + 0.5)
+}
+
+func snorm(v float32, max float32) int32 {
+	if v < -1 {
+		v = -1
+	}
+	if v > 1 {
+		v = 1
+	}
+	return int32(math.Round(float64(v * max)))
+}
+
+// halfFloat converts a float32 to the bits of the nearest half-precision float.
+func halfFloat(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+
+	switch {
+	case bits>>23&0xff == 0xff:
+		// infinity or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		// too large, round to infinity
+		return sign | 0x7c00
+	case exp <= 0:
+		// subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		half := uint16(mant >> shift)
+		if mant>>(shift-1)&1 != 0 {
+			half++
+		}
+		return sign | half
+	default:
+		half := sign | uint16(exp)<<10 | uint16(mant>>13)
+		if mant&0x1000 != 0 {
+			// round, a carry correctly propagates into the exponent
+			half++
+		}
+		return half
+	}
+}
@@ -0,0 +1,229 @@
+// Package particles implements a simple particle system simulated entirely on the GPU using a
+// compute shader.
+//
+// Particle positions live directly in the vertex buffer of a glhf.VertexSlice, so they're drawn
+// without ever being copied through the CPU. Velocities live in a separate shader storage buffer.
+//
+// The package requires compute shaders (see glhf.ComputeSupported).
+package particles
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"github.com/faiface/glhf"
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/pkg/errors"
+)
+
+// System is a set of particles simulated on the GPU.
+//
+// Each particle has a position and a velocity, both stored as a vec4. The XYZ components of the
+// velocity are integrated into the XYZ components of the position each Update. The W components
+// are never touched by the simulation, so they can carry arbitrary per-particle data, such as
+// the size or the age of the particle.
+type System struct {
+	update   *glhf.Compute
+	slice    *glhf.VertexSlice
+	velocity *glhf.Buffer
+	count    int
+	bindings [2]int // shader storage buffer binding points of the positions and the velocities
+
+	damping      float32
+	acceleration mgl32.Vec3
+}
+
+// New creates a new System of count particles drawn with the supplied shader. All particles
+// initially sit still at the origin. The count must be positive.
+//
+// The vertex format of the shader must consist of a single glhf.Vec4 attribute, which receives
+// the position of the particle. The particles are drawn as points, so the shader will usually
+// set gl_PointSize.
+func New(shader *glhf.Shader, count int) (*System, error) {
+	vertexFmt := shader.VertexFormat()
+	if len(vertexFmt) != 1 || vertexFmt[0].Type != glhf.Vec4 {
+		return nil, errors.New("failed to create particle system: vertex format must be a single Vec4 attribute")
+	}
+	if count <= 0 {
+		return nil, errors.Errorf("failed to create particle system: invalid count %d", count)
+	}
+
+	var bindings [2]int
+	for i := range bindings {
+		binding, err := glhf.AcquireBinding(glhf.StorageBufferBinding)
+		if err != nil {
+			for _, b := range bindings[:i] {
+				glhf.ReleaseBinding(glhf.StorageBufferBinding, b)
+			}
+			return nil, errors.Wrap(err, "failed to create particle system")
+		}
+		bindings[i] = binding
+	}
+
+	source := fmt.Sprintf(updateComputeShader, bindings[0], bindings[1])
+	update, err := glhf.NewComputeProgram(updateUniformFormat, source)
+	if err != nil {
+		glhf.ReleaseBinding(glhf.StorageBufferBinding, bindings[0])
+		glhf.ReleaseBinding(glhf.StorageBufferBinding, bindings[1])
+		return nil, errors.Wrap(err, "failed to create particle system")
+	}
+
+	s := &System{
+		update:   update,
+		slice:    glhf.MakeVertexSlice(shader, count, count),
+		velocity: glhf.NewBuffer(glhf.StorageTarget, count*4*4, glhf.DynamicDraw),
+		count:    count,
+		bindings: bindings,
+	}
+	s.slice.SetPrimitive(glhf.Points)
+
+	runtime.SetFinalizer(s, (*System).delete)
+
+	return s, nil
+}
+
+func (s *System) delete() {
+	mainthread.CallNonBlock(s.release)
+}
+
+// Delete deletes the System immediately instead of waiting for the garbage collector, together
+// with its buffers and compute program, and releases its binding points. The System must not be
+// used afterwards. Deleting a System multiple times is harmless.
+func (s *System) Delete() {
+	runtime.SetFinalizer(s, nil)
+	s.release()
+}
+
+func (s *System) release() {
+	if s.velocity == nil {
+		// already deleted, don't release the binding points twice
+		return
+	}
+	s.velocity.Delete()
+	s.velocity = nil
+	s.slice.Delete()
+	s.update.Delete()
+	glhf.ReleaseBinding(glhf.StorageBufferBinding, s.bindings[0])
+	glhf.ReleaseBinding(glhf.StorageBufferBinding, s.bindings[1])
+}
+
+// Count returns the number of particles in the System.
+func (s *System) Count() int {
+	return s.count
+}
+
+// SetDamping sets how fast the particles slow down. Each second, the velocity gets multiplied by
+// exp(-damping), so 0 means no damping at all.
+func (s *System) SetDamping(damping float32) {
+	s.damping = damping
+}
+
+// Damping returns the damping of the System.
+func (s *System) Damping() float32 {
+	return s.damping
+}
+
+// SetAcceleration sets the constant acceleration (e.g. gravity) applied to all particles, in units
+// per second squared.
+func (s *System) SetAcceleration(acceleration mgl32.Vec3) {
+	s.acceleration = acceleration
+}
+
+// Acceleration returns the constant acceleration applied to all particles.
+func (s *System) Acceleration() mgl32.Vec3 {
+	return s.acceleration
+}
+
+// SetParticles sets the positions and the velocities of all particles. Each slice must contain
+// exactly 4 elements per particle (a vec4).
+func (s *System) SetParticles(positions, velocities []float32) {
+	if len(positions) != s.count*4 || len(velocities) != s.count*4 {
+		panic("set particles: wrong number of particles")
+	}
+
+	s.slice.Begin()
+	s.slice.SetVertexData(positions)
+	s.slice.End()
+
+	s.velocity.Begin()
+	s.velocity.SetData(0, unsafe.Slice((*byte)(unsafe.Pointer(&velocities[0])), len(velocities)*4))
+	s.velocity.End()
+}
+
+// Update advances the simulation by dt seconds.
+//
+// The System acquires its own two shader storage buffer binding points (see glhf.AcquireBinding),
+// which are overwritten by this method.
+func (s *System) Update(dt float32) {
+	s.update.Begin()
+	s.update.SetUniformAttr(updateUniformDt, dt)
+	s.update.SetUniformAttr(updateUniformDamping, s.damping)
+	s.update.SetUniformAttr(updateUniformAcceleration, s.acceleration)
+	s.update.SetUniformAttr(updateUniformCount, int32(s.count))
+	s.slice.BindBase(s.bindings[0])
+	s.velocity.BindBase(s.bindings[1])
+	s.update.Dispatch((s.count+updateWorkGroupSize-1)/updateWorkGroupSize, 1, 1)
+	s.update.End()
+
+	glhf.MemoryBarrier(glhf.VertexAttribArrayBarrier | glhf.ShaderStorageBarrier)
+}
+
+// Draw draws all particles as points.
+//
+// The shader supplied to New must be bound before calling this method.
+func (s *System) Draw() {
+	s.slice.Begin()
+	s.slice.Draw()
+	s.slice.End()
+}
+
+const updateWorkGroupSize = 64
+
+const (
+	updateUniformDt = iota
+	updateUniformDamping
+	updateUniformAcceleration
+	updateUniformCount
+)
+
+var updateUniformFormat = glhf.AttrFormat{
+	updateUniformDt:           {Name: "dt", Type: glhf.Float},
+	updateUniformDamping:      {Name: "damping", Type: glhf.Float},
+	updateUniformAcceleration: {Name: "acceleration", Type: glhf.Vec3},
+	updateUniformCount:        {Name: "count", Type: glhf.Int},
+}
+
+const updateComputeShader = `
+#version 430 core
+
+layout(local_size_x = 64) in;
+
+layout(std430, binding = %d) buffer Positions {
+	vec4 position[];
+};
+
+layout(std430, binding = %d) buffer Velocities {
+	vec4 velocity[];
+};
+
+uniform float dt;
+uniform float damping;
+uniform vec3 acceleration;
+uniform int count;
+
+void main() {
+	int i = int(gl_GlobalInvocationID.x);
+	if (i >= count) {
+		return;
+	}
+
+	vec3 v = velocity[i].xyz;
+	v += acceleration * dt;
+	v *= exp(-damping * dt);
+
+	velocity[i].xyz = v;
+	position[i].xyz += v * dt;
+}
+`
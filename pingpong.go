@@ -0,0 +1,67 @@
+package glhf
+
+// PingPong holds two Frames of the same size and format for iterative effects, such as separable
+// blurs, simulations or feedback, where each pass reads the result of the previous one and draws
+// into the other Frame. A pass looks like this:
+//
+//   pp.Destination().Begin()
+//   pp.Source().Texture().Begin()
+//   // draw
+//   pp.Source().Texture().End()
+//   pp.Destination().End()
+//   pp.Swap()
+//
+// After the Swap, the result of the pass is in the Source Frame.
+type PingPong struct {
+	frames [2]*Frame
+	src    int
+}
+
+// NewPingPong creates a new PingPong with two Frames of the given size and format.
+func NewPingPong(width, height int, smooth bool, format TextureFormat) *PingPong {
+	return &PingPong{
+		frames: [2]*Frame{
+			NewFrameFormat(width, height, smooth, format),
+			NewFrameFormat(width, height, smooth, format),
+		},
+	}
+}
+
+// Source returns the Frame holding the result of the last pass, i.e. the one to read from.
+func (pp *PingPong) Source() *Frame {
+	return pp.frames[pp.src]
+}
+
+// Destination returns the Frame the next pass draws into. Its content is the result of the pass
+// before the last one.
+func (pp *PingPong) Destination() *Frame {
+	return pp.frames[1-pp.src]
+}
+
+// Swap exchanges the Source and the Destination Frame. Call it after each pass, once the
+// Destination Frame is unbound.
+func (pp *PingPong) Swap() {
+	pp.src = 1 - pp.src
+}
+
+// Resize resizes both Frames, see Frame.Resize.
+func (pp *PingPong) Resize(width, height int, preserve bool) {
+	pp.frames[0].Resize(width, height, preserve)
+	pp.frames[1].Resize(width, height, preserve)
+}
+
+// Width returns the width of the Frames in pixels.
+func (pp *PingPong) Width() int {
+	return pp.frames[0].Width()
+}
+
+// Height returns the height of the Frames in pixels.
+func (pp *PingPong) Height() int {
+	return pp.frames[0].Height()
+}
+
+// Delete deletes both Frames immediately, see Frame.Delete.
+func (pp *PingPong) Delete() {
+	pp.frames[0].Delete()
+	pp.frames[1].Delete()
+}
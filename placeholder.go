@@ -0,0 +1,152 @@
+package glhf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Placeholder resources are substituted for the ones that failed to load, so that an app keeps
+// running and the failure is clearly visible on the screen instead of crashing or rendering
+// nothing. They are created lazily on the first use and shared, so deleting them does nothing.
+var (
+	checkerTexture    *Texture
+	whiteTexture      *Texture
+	flatNormalTexture *Texture
+	errorShaders      = make(map[string]*Shader)
+	errorComputes     = make(map[string]*Compute)
+)
+
+// CheckerTexture returns the standard placeholder for missing textures: a 16x16 magenta and black
+// checkerboard of 2x2 squares, drawn pixely.
+func CheckerTexture() *Texture {
+	if checkerTexture == nil {
+		checkerTexture = NewTexture(16, 16, false, checkerPixels(16, 16, RGBA8))
+		checkerTexture.placeholder = true
+	}
+	return checkerTexture
+}
+
+// checkerTextureArray returns a new 16x16 TextureArray with a single layer containing the
+// checkerboard of CheckerTexture.
+func checkerTextureArray() *TextureArray {
+	ta := NewTextureArray(16, 16, 1, false, RGBA8)
+	ta.Begin()
+	ta.SetLayerPixels(0, 0, 0, 16, 16, checkerPixels(16, 16, RGBA8))
+	ta.End()
+	return ta
+}
+
+// checkerPixels returns the pixels of a magenta and black checkerboard of 2x2 squares with the
+// given size and format.
+func checkerPixels(width, height int, format TextureFormat) []uint8 {
+	const square = 2
+	bpp := format.BytesPerPixel()
+	pixels := make([]uint8, width*height*bpp)
+	if format.element() != reflect.Uint8 {
+		// not a byte format, leave it black
+		return pixels
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := pixels[(y*width+x)*bpp : (y*width+x+1)*bpp]
+			color := []uint8{255, 0, 255, 255}
+			if (x/square+y/square)%2 != 0 {
+				color = []uint8{0, 0, 0, 255}
+			}
+			copy(pixel, color)
+			if bpp == 2 {
+				// R and G, make it red instead
+				pixel[1] = 0
+			}
+		}
+	}
+	return pixels
+}
+
+// WhiteTexture returns a 1x1 opaque white Texture. It's a neutral placeholder for textures that
+// multiply the color, such as the diffuse map of an untextured material.
+func WhiteTexture() *Texture {
+	if whiteTexture == nil {
+		whiteTexture = NewTexture(1, 1, false, []uint8{255, 255, 255, 255})
+		whiteTexture.placeholder = true
+	}
+	return whiteTexture
+}
+
+// FlatNormalTexture returns a 1x1 Texture containing the tangent-space normal pointing straight
+// up (0, 0, 1), encoded as usual into (128, 128, 255). It's a neutral placeholder for normal maps.
+func FlatNormalTexture() *Texture {
+	if flatNormalTexture == nil {
+		flatNormalTexture = NewTexture(1, 1, false, []uint8{128, 128, 255, 255})
+		flatNormalTexture.placeholder = true
+	}
+	return flatNormalTexture
+}
+
+// ErrorShader returns the placeholder for a Shader that failed to compile. It has the supplied
+// vertex and uniform formats, so it can be used as a drop-in replacement, and renders everything
+// in solid magenta.
+//
+// The vertex attribute named "position" (or the first floating point vector attribute, if there's
+// no such) is used as the clip-space position. Uniforms are accepted but ignored.
+func ErrorShader(vertexFmt, uniformFmt AttrFormat) *Shader {
+	key := fmt.Sprint(vertexFmt, uniformFmt)
+	if shader := errorShaders[key]; shader != nil {
+		return shader
+	}
+
+	var position *Attr
+	for i := range vertexFmt {
+		switch vertexFmt[i].Type {
+		case Vec2, Vec3, Vec4:
+			if position == nil || vertexFmt[i].Name == "position" {
+				position = &vertexFmt[i]
+			}
+		}
+	}
+
+	vertexShader := "#version 330 core\nvoid main() {\n\tgl_Position = vec4(0.0, 0.0, 0.0, 1.0);\n}\n"
+	if position != nil {
+		expr := map[AttrType]string{
+			Vec2: "vec4(%s, 0.0, 1.0)",
+			Vec3: "vec4(%s, 1.0)",
+			Vec4: "%s",
+		}[position.Type]
+		glslType := map[AttrType]string{Vec2: "vec2", Vec3: "vec3", Vec4: "vec4"}[position.Type]
+		vertexShader = fmt.Sprintf(
+			"#version 330 core\nin %s %s;\nvoid main() {\n\tgl_Position = "+expr+";\n}\n",
+			glslType, position.Name, position.Name,
+		)
+	}
+	const fragmentShader = "#version 330 core\nout vec4 color;\nvoid main() {\n\tcolor = vec4(1.0, 0.0, 1.0, 1.0);\n}\n"
+
+	shader, err := newShader(vertexFmt, uniformFmt, vertexShader, fragmentShader)
+	if err != nil {
+		// the error shader is trivial, so this means the OpenGL context itself is broken
+		panic(err)
+	}
+	shader.placeholder = true
+	errorShaders[key] = shader
+	return shader
+}
+
+// ErrorCompute returns the placeholder for a Compute program that failed to compile. It has the
+// supplied uniform format, so it can be used as a drop-in replacement, and does nothing when
+// dispatched. Uniforms are accepted but ignored.
+func ErrorCompute(uniformFmt AttrFormat) *Compute {
+	key := fmt.Sprint(uniformFmt)
+	if compute := errorComputes[key]; compute != nil {
+		return compute
+	}
+
+	const computeShader = "#version 430 core\nlayout(local_size_x = 1) in;\nvoid main() {}\n"
+
+	compute, err := newComputeProgram(uniformFmt, computeShader)
+	if err != nil {
+		// the error compute program is trivial, so this means the OpenGL context itself is broken
+		panic(err)
+	}
+	compute.placeholder = true
+	errorComputes[key] = compute
+	return compute
+}
@@ -0,0 +1,55 @@
+package glhf
+
+import (
+	"log"
+	"os"
+)
+
+// FailurePolicy decides what glhf does when creating a resource fails.
+type FailurePolicy int
+
+// List of all failure policies.
+const (
+	// Strict returns errors where possible and panics early otherwise. This is the default and
+	// it's meant for development, where failures should be noticed and fixed.
+	Strict FailurePolicy = iota
+
+	// Resilient logs the failure through the Logger and substitutes a placeholder (see
+	// CheckerTexture and ErrorShader), so that the app keeps running. It's meant for shipped
+	// apps, where a missing texture is better than a crash.
+	Resilient
+)
+
+// Logger is where glhf reports failures handled by the Resilient policy. The standard *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var (
+	failurePolicy FailurePolicy
+	logger        Logger = log.New(os.Stderr, "glhf: ", log.LstdFlags)
+)
+
+// SetFailurePolicy sets the package-wide FailurePolicy. It applies to shader and compute program
+// compilation, texture creation and loading, and frame creation.
+func SetFailurePolicy(policy FailurePolicy) {
+	failurePolicy = policy
+}
+
+// CurrentFailurePolicy returns the package-wide FailurePolicy.
+func CurrentFailurePolicy() FailurePolicy {
+	return failurePolicy
+}
+
+// SetLogger sets the Logger used to report failures. The default one writes to the standard
+// error output. If nil, failures aren't reported at all.
+func SetLogger(l Logger) {
+	logger = l
+}
+
+func logf(format string, v ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, v...)
+	}
+}
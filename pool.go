@@ -0,0 +1,117 @@
+package glhf
+
+// SlicePool hands out VertexSlices that are sub-ranges of a few large shared vertex arrays,
+// instead of creating a new vertex array for each of them.
+//
+// A scene with thousands of small meshes otherwise spends most of its time allocating buffers
+// and switching between them. With a SlicePool, all meshes in the same block share the vertex
+// array, so binding one VertexSlice after another from the same block is free.
+//
+// The VertexSlices returned by Get must not be resized using SetLen beyond their length, since
+// the space after them belongs to other VertexSlices. Return them by Put when no longer needed.
+type SlicePool struct {
+	shader   *Shader
+	blockCap int
+	blocks   []*slicePoolBlock
+	used     map[*VertexSlice]slicePoolRange
+}
+
+type slicePoolBlock struct {
+	vs   *VertexSlice
+	free []slicePoolRange // sorted, non-adjacent free ranges
+}
+
+type slicePoolRange struct {
+	block *slicePoolBlock
+	i, j  int
+}
+
+// NewSlicePool creates a new empty SlicePool for the given Shader. The pool allocates vertex
+// arrays with the capacity of blockCap vertices (or more, if a larger VertexSlice is requested).
+func NewSlicePool(shader *Shader, blockCap int) *SlicePool {
+	if blockCap <= 0 {
+		panic("failed to make slice pool: block capacity must be positive")
+	}
+	return &SlicePool{
+		shader:   shader,
+		blockCap: blockCap,
+		used:     make(map[*VertexSlice]slicePoolRange),
+	}
+}
+
+// Get returns a VertexSlice with the given length from the SlicePool. Its content is undefined.
+func (p *SlicePool) Get(len int) *VertexSlice {
+	if len <= 0 {
+		panic("slice pool get: length must be positive")
+	}
+
+	for _, block := range p.blocks {
+		for k, free := range block.free {
+			if free.j-free.i < len {
+				continue
+			}
+			vs := block.vs.Slice(free.i, free.i+len)
+			p.used[vs] = slicePoolRange{block, free.i, free.i + len}
+			if free.j-free.i == len {
+				block.free = append(block.free[:k], block.free[k+1:]...)
+			} else {
+				block.free[k].i += len
+			}
+			return vs
+		}
+	}
+
+	// no space left, allocate a new block
+	cap := p.blockCap
+	if cap < len {
+		cap = len
+	}
+	block := &slicePoolBlock{vs: MakeVertexSlice(p.shader, cap, cap)}
+	if len < cap {
+		block.free = []slicePoolRange{{block, len, cap}}
+	}
+	p.blocks = append(p.blocks, block)
+
+	vs := block.vs.Slice(0, len)
+	p.used[vs] = slicePoolRange{block, 0, len}
+	return vs
+}
+
+// Put returns a VertexSlice obtained by Get back to the SlicePool, so that its space can be
+// reused. The VertexSlice must not be used after that.
+func (p *SlicePool) Put(vs *VertexSlice) {
+	r, ok := p.used[vs]
+	if !ok {
+		panic("slice pool put: vertex slice not from this pool")
+	}
+	delete(p.used, vs)
+
+	block := r.block
+	k := 0
+	for k < len(block.free) && block.free[k].i < r.i {
+		k++
+	}
+	block.free = append(block.free, slicePoolRange{})
+	copy(block.free[k+1:], block.free[k:])
+	block.free[k] = r
+
+	// merge with the neighbours
+	if k+1 < len(block.free) && block.free[k].j == block.free[k+1].i {
+		block.free[k].j = block.free[k+1].j
+		block.free = append(block.free[:k+1], block.free[k+2:]...)
+	}
+	if k > 0 && block.free[k-1].j == block.free[k].i {
+		block.free[k-1].j = block.free[k].j
+		block.free = append(block.free[:k], block.free[k+1:]...)
+	}
+}
+
+// Len returns the number of VertexSlices currently handed out by the SlicePool.
+func (p *SlicePool) Len() int {
+	return len(p.used)
+}
+
+// Blocks returns the number of vertex arrays allocated by the SlicePool.
+func (p *SlicePool) Blocks() int {
+	return len(p.blocks)
+}
@@ -0,0 +1,50 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// Prewarm forces the driver to finish the work it deferred when the supplied Shaders and
+// Textures were created, such as the final compilation of shaders or the upload of texture
+// data.
+//
+// Many drivers postpone this work until an object is first used for drawing, which shows up as
+// a hitch the first time something new appears on the screen. Prewarm avoids it by drawing a
+// single degenerate triangle off-screen with each Shader and each Texture and waiting for the GPU
+// to finish. Call it right after loading, e.g. behind a loading screen.
+//
+// Textures are drawn using the first Shader, so if no Shader is supplied, the Textures are not
+// touched. The current framebuffer, viewport and scissor are left intact.
+func Prewarm(shaders []*Shader, textures []*Texture) {
+	if len(shaders) > 0 {
+		var viewport, scissor [4]int32
+		gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+		gl.GetIntegerv(gl.SCISSOR_BOX, &scissor[0])
+
+		frame := NewFrame(1, 1, false)
+		frame.Begin()
+		Bounds(0, 0, 1, 1)
+
+		for i, shader := range shaders {
+			slice := MakeVertexSlice(shader, 3, 3)
+			shader.Begin()
+			slice.Begin()
+			slice.Draw()
+			if i == 0 {
+				for _, texture := range textures {
+					texture.Begin()
+					slice.Draw()
+					texture.End()
+				}
+			}
+			slice.End()
+			shader.End()
+			slice.Delete()
+		}
+
+		frame.End()
+		frame.Delete()
+		gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+		gl.Scissor(scissor[0], scissor[1], scissor[2], scissor[3])
+	}
+
+	gl.Finish()
+}
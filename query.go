@@ -0,0 +1,115 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// QueryKind is a kind of what a Query counts.
+type QueryKind int
+
+// List of all query kinds.
+const (
+	// SamplesPassed counts the samples that passed the depth and stencil tests.
+	SamplesPassed QueryKind = iota
+
+	// AnySamplesPassed only tells whether any sample passed the depth and stencil tests. It may
+	// be cheaper than SamplesPassed.
+	AnySamplesPassed
+)
+
+func (kind QueryKind) glTarget() uint32 {
+	switch kind {
+	case SamplesPassed:
+		return gl.SAMPLES_PASSED
+	case AnySamplesPassed:
+		return gl.ANY_SAMPLES_PASSED
+	}
+	panic("invalid query kind")
+}
+
+// Query is an OpenGL occlusion query. It counts the samples drawn between Begin and End.
+//
+// A typical use is to draw the bounding box of an expensive object with color and depth writes
+// disabled inside a Query, and then draw the object itself inside ConditionalRender with that
+// Query, possibly a frame later.
+type Query struct {
+	query  uint32
+	target uint32
+	active bool
+}
+
+// NewQuery creates a new Query of the given kind.
+func NewQuery(kind QueryKind) *Query {
+	q := &Query{target: kind.glTarget()}
+
+	gl.GenQueries(1, &q.query)
+
+	runtime.SetFinalizer(q, (*Query).delete)
+
+	return q
+}
+
+func (q *Query) delete() {
+	mainthread.CallNonBlock(q.release)
+}
+
+// Delete deletes the Query immediately instead of waiting for the garbage collector. The Query
+// must not be used afterwards. Deleting a Query multiple times is harmless.
+func (q *Query) Delete() {
+	runtime.SetFinalizer(q, nil)
+	q.release()
+}
+
+func (q *Query) release() {
+	gl.DeleteQueries(1, &q.query)
+	q.query = 0
+}
+
+// ID returns the OpenGL ID of this Query.
+func (q *Query) ID() uint32 {
+	return q.query
+}
+
+// Available returns whether the result of the Query is available. It never waits for the GPU.
+func (q *Query) Available() bool {
+	var available int32
+	gl.GetQueryObjectiv(q.query, gl.QUERY_RESULT_AVAILABLE, &available)
+	return available != gl.FALSE
+}
+
+// Result returns the result of the Query: the number of samples that passed, or 1 or 0 for
+// AnySamplesPassed. If the result isn't available yet, this method waits for it.
+func (q *Query) Result() int {
+	var result uint32
+	gl.GetQueryObjectuiv(q.query, gl.QUERY_RESULT, &result)
+	return int(result)
+}
+
+// Begin starts counting the samples. Only one Query of each kind can be active at a time.
+func (q *Query) Begin() {
+	if q.active {
+		panic("query begin: query already active")
+	}
+	q.active = true
+	gl.BeginQuery(q.target, q.query)
+}
+
+// End stops counting the samples.
+func (q *Query) End() {
+	q.active = false
+	gl.EndQuery(q.target)
+}
+
+// ConditionalRender calls fn, but the GPU skips all draws issued by it if the Query counted no
+// samples.
+//
+// If the result of the Query isn't available yet when the draws are executed, the GPU doesn't
+// wait for it and simply performs them. The CPU never waits either, fn is always called.
+func ConditionalRender(query *Query, fn func()) {
+	gl.BeginConditionalRender(query.query, gl.QUERY_NO_WAIT)
+	fn()
+	gl.EndConditionalRender()
+}
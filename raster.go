@@ -0,0 +1,157 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// CullFace represents which faces of polygons are culled, i.e. not drawn at all.
+type CullFace int
+
+// Here's the list of all cull faces.
+const (
+	// CullNone draws all faces. It's the default.
+	CullNone CullFace = iota
+
+	// CullBack culls the faces facing away from the viewer, which are hidden anyway when drawing
+	// closed meshes. This halves the number of drawn polygons.
+	CullBack
+
+	// CullFront culls the faces facing the viewer, e.g. for drawing the inside of a skybox or
+	// outlines by the inverted hull technique.
+	CullFront
+
+	// CullFrontAndBack culls all polygons, only points and lines are drawn.
+	CullFrontAndBack
+)
+
+// Winding represents the order of the vertices of a polygon as seen on the screen.
+type Winding int
+
+// Here's the list of all windings.
+const (
+	// CounterClockwise polygons are front-facing. It's the default.
+	CounterClockwise Winding = iota
+
+	// Clockwise polygons are front-facing.
+	Clockwise
+)
+
+// SetCullFace sets which faces of polygons are culled. Whether a polygon faces the viewer is
+// determined by its winding, see SetFrontFace.
+func SetCullFace(cull CullFace) {
+	if cull == CullNone {
+		gl.Disable(gl.CULL_FACE)
+		return
+	}
+	switch cull {
+	case CullBack:
+		gl.CullFace(gl.BACK)
+	case CullFront:
+		gl.CullFace(gl.FRONT)
+	case CullFrontAndBack:
+		gl.CullFace(gl.FRONT_AND_BACK)
+	default:
+		panic("set cull face: invalid cull face")
+	}
+	gl.Enable(gl.CULL_FACE)
+}
+
+// SetFrontFace sets the winding of the front-facing polygons, as seen on the screen. Meshes
+// exported by most tools have counter-clockwise front faces, which is the default.
+func SetFrontFace(winding Winding) {
+	switch winding {
+	case CounterClockwise:
+		gl.FrontFace(gl.CCW)
+	case Clockwise:
+		gl.FrontFace(gl.CW)
+	default:
+		panic("set front face: invalid winding")
+	}
+}
+
+// PolygonMode represents how polygons are rasterized.
+type PolygonMode int
+
+// Here's the list of all polygon modes.
+const (
+	// PolygonFill fills the polygons. It's the default.
+	PolygonFill PolygonMode = iota
+
+	// PolygonLine draws only the edges of the polygons, i.e. a wireframe.
+	PolygonLine
+
+	// PolygonPoint draws only the vertices of the polygons.
+	PolygonPoint
+)
+
+func (pm PolygonMode) glMode() uint32 {
+	switch pm {
+	case PolygonFill:
+		return gl.FILL
+	case PolygonLine:
+		return gl.LINE
+	case PolygonPoint:
+		return gl.POINT
+	}
+	panic("invalid polygon mode")
+}
+
+// polygonModes is the stack of polygon modes saved by PushPolygonMode.
+var polygonModes []int32
+
+// SetPolygonMode sets how polygons are rasterized, e.g. PolygonLine for a debug wireframe view.
+// Points and lines are unaffected.
+func SetPolygonMode(mode PolygonMode) {
+	gl.PolygonMode(gl.FRONT_AND_BACK, mode.glMode())
+}
+
+// PushPolygonMode saves the current polygon mode and sets the new one, see SetPolygonMode.
+// PopPolygonMode restores the saved one. This way, a wireframe can be switched on for a single
+// draw or a whole frame:
+//
+//   glhf.PushPolygonMode(glhf.PolygonLine)
+//   // draw
+//   glhf.PopPolygonMode()
+func PushPolygonMode(mode PolygonMode) {
+	var prev [2]int32 // some drivers report the front and back modes separately
+	gl.GetIntegerv(gl.POLYGON_MODE, &prev[0])
+	polygonModes = append(polygonModes, prev[0])
+	SetPolygonMode(mode)
+}
+
+// PopPolygonMode restores the polygon mode saved by the last PushPolygonMode.
+func PopPolygonMode() {
+	if len(polygonModes) == 0 {
+		panic("pop polygon mode: no polygon mode pushed")
+	}
+	gl.PolygonMode(gl.FRONT_AND_BACK, uint32(polygonModes[len(polygonModes)-1]))
+	polygonModes = polygonModes[:len(polygonModes)-1]
+}
+
+// SetLineWidth sets the width of Lines and LineStrip primitives in pixels, 1 by default. Core
+// OpenGL contexts only guarantee the width of 1, wider lines may be clamped, see MaxLineWidth.
+func SetLineWidth(width float32) {
+	gl.LineWidth(width)
+}
+
+// MaxLineWidth returns the maximum line width supported by the current OpenGL context.
+func MaxLineWidth() float32 {
+	var widthRange [2]float32
+	gl.GetFloatv(gl.ALIASED_LINE_WIDTH_RANGE, &widthRange[0])
+	return widthRange[1]
+}
+
+// SetPointSize sets the size of Points primitives in pixels, 1 by default. It's ignored while the
+// program point size is enabled, see SetProgramPointSize.
+func SetPointSize(size float32) {
+	gl.PointSize(size)
+}
+
+// SetProgramPointSize sets whether the size of Points primitives is taken from the gl_PointSize
+// output of the vertex shader, which allows the size to vary per vertex, e.g. for point sprite
+// particles shrinking with distance. It's disabled by default.
+func SetProgramPointSize(enabled bool) {
+	if enabled {
+		gl.Enable(gl.PROGRAM_POINT_SIZE)
+	} else {
+		gl.Disable(gl.PROGRAM_POINT_SIZE)
+	}
+}
@@ -0,0 +1,215 @@
+package glhf
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// Element is a type of a single component of texture pixels or vertex attributes.
+type Element interface {
+	~uint8 | ~int8 | ~uint16 | ~int16 | ~uint32 | ~int32 | ~float32
+}
+
+// ReadTexture returns the whole content of the Texture as a slice of components, e.g. []float32
+// for an R32F Texture, []uint8 for an RGBA8 one, or []int32 for an R32I one. The pixels are in
+// tightly packed rows, starting from the bottom one (or the top one, see SetConvention).
+//
+// If the element type doesn't match the format of the Texture, an error is returned.
+//
+// The Texture must be bound before calling this function.
+func ReadTexture[T Element](t *Texture) ([]T, error) {
+	var zero T
+	if elem := t.format.element(); elem != reflect.TypeOf(zero).Kind() {
+		return nil, errors.Errorf("failed to read texture: texture has %v components, not %T", elem, zero)
+	}
+	data := make([]T, t.width*t.height*t.format.BytesPerPixel()/int(unsafe.Sizeof(zero)))
+	if len(data) == 0 {
+		return data, nil
+	}
+	defer pixelStore(true, 1, 0)()
+	gl.GetTexImage(
+		gl.TEXTURE_2D,
+		0,
+		t.format.pixelFormat(),
+		t.format.pixelType(),
+		gl.Ptr(&data[0]),
+	)
+	flipRows(data, len(data)/t.height)
+	return data, nil
+}
+
+// ReadBuffer returns the content of the VertexSlice as a slice of components, e.g. []float32 for
+// floating point attributes, []int32 for Int and IVec attributes, or []uint8 for UByte4Norm
+// attributes.
+//
+// All attributes of the VertexSlice must have the same component type and it must match the
+// element type, otherwise an error is returned.
+//
+// The VertexSlice must be bound before calling this function.
+func ReadBuffer[T Element](vs *VertexSlice) ([]T, error) {
+	var zero T
+	for _, attr := range vs.VertexFormat() {
+		if attr.Name == "" {
+			// padding
+			continue
+		}
+		if elem := attr.Type.element(); elem != reflect.TypeOf(zero).Kind() {
+			return nil, errors.Errorf("failed to read buffer: attribute %s has %v components, not %T", attr.Name, elem, zero)
+		}
+	}
+	data := vs.VertexData()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&data[0])), len(data)*4/int(unsafe.Sizeof(zero))), nil
+}
+
+// element returns the kind of the Go type corresponding to the components of the format.
+func (f TextureFormat) element() reflect.Kind {
+	switch f {
+	case R32F, RGBA32F:
+		return reflect.Float32
+	case R32I:
+		return reflect.Int32
+	case R32UI, R11FG11FB10F:
+		return reflect.Uint32
+	case RGBA16F:
+		return reflect.Uint16
+	}
+	return reflect.Uint8
+}
+
+// element returns the kind of the Go type corresponding to the components of the attribute type.
+func (at AttrType) element() reflect.Kind {
+	switch at {
+	case Int, IVec2, IVec3, IVec4:
+		return reflect.Int32
+	case Uint, UVec2, UVec3, UVec4:
+		return reflect.Uint32
+	case UByte4Norm:
+		return reflect.Uint8
+	case Byte4Norm:
+		return reflect.Int8
+	case UShort2Norm, UShort4Norm, Half2, Half4:
+		return reflect.Uint16
+	case Short2Norm, Short4Norm:
+		return reflect.Int16
+	}
+	return reflect.Float32
+}
+
+// CubeFace is one of the six faces of a cubemap texture.
+type CubeFace int
+
+// List of all cubemap faces, in the order of OpenGL cubemap layers.
+const (
+	CubePositiveX CubeFace = iota
+	CubeNegativeX
+	CubePositiveY
+	CubeNegativeY
+	CubePositiveZ
+	CubeNegativeZ
+)
+
+// ReadLayer returns the content of a single layer of a layered OpenGL texture as tightly packed
+// rows of pixels in the given format, starting from the bottom row (or the top one, see
+// SetConvention). The width and height are the size of the mipmap level.
+//
+// The texture is specified by its target and OpenGL ID, so that it works with textures created
+// outside glhf too. For gl.TEXTURE_CUBE_MAP, the layer is a CubeFace, for gl.TEXTURE_2D_ARRAY it's
+// the index of the array layer and for gl.TEXTURE_3D it's the depth of the slice.
+//
+// This is mainly useful for saving baked probes or generated texture arrays to disk, or for
+// verifying them in tests. The texture is read through a temporary framebuffer, so the format
+// must be color-renderable.
+func ReadLayer(target, id uint32, level, layer, width, height int, format TextureFormat) []uint8 {
+	rf := binder{
+		restoreLoc: gl.READ_FRAMEBUFFER_BINDING,
+		bindFunc: func(obj uint32) {
+			gl.BindFramebuffer(gl.READ_FRAMEBUFFER, obj)
+		},
+	}
+	gl.GenFramebuffers(1, &rf.obj)
+	defer gl.DeleteFramebuffers(1, &rf.obj)
+	rf.bind()
+	defer rf.restore()
+
+	switch target {
+	case gl.TEXTURE_CUBE_MAP:
+		if layer < 0 || layer > int(CubeNegativeZ) {
+			panic("read layer: invalid cube face")
+		}
+		face := gl.TEXTURE_CUBE_MAP_POSITIVE_X + uint32(layer)
+		gl.FramebufferTexture2D(gl.READ_FRAMEBUFFER, gl.COLOR_ATTACHMENT0, face, id, int32(level))
+	case gl.TEXTURE_2D_ARRAY, gl.TEXTURE_3D:
+		gl.FramebufferTextureLayer(gl.READ_FRAMEBUFFER, gl.COLOR_ATTACHMENT0, id, int32(level), int32(layer))
+	default:
+		panic("read layer: unsupported texture target")
+	}
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+
+	pixels := make([]uint8, width*height*format.BytesPerPixel())
+	if len(pixels) == 0 {
+		return pixels
+	}
+	defer pixelStore(true, 1, 0)()
+	gl.ReadPixels(0, 0, int32(width), int32(height), format.pixelFormat(), format.pixelType(), gl.Ptr(&pixels[0]))
+	flipRows(pixels, width*format.BytesPerPixel())
+	return pixels
+}
+
+// DepthPixels returns the depths of a sub-region of the Frame, in the range 0 to 1, as tightly
+// packed rows starting from the bottom one (or the top one, see SetConvention). This is useful
+// for picking by depth on the CPU, or for inspecting shadow maps.
+//
+// The Frame must have a depth buffer and it must not be multisampled. Like Texture.Pixels, this
+// stalls until the GPU has finished all the drawing before it.
+func (f *Frame) DepthPixels(x, y, w, h int) []float32 {
+	if f.samples > 0 {
+		panic("depth pixels: multisampled frame, use Resolve")
+	}
+	if f.depth == nil && f.depthRb == nil && !f.isDefault {
+		panic("depth pixels: frame without depth buffer")
+	}
+	depths := make([]float32, w*h)
+	readFramePixels(f, x, y, w, h, gl.DEPTH_COMPONENT, gl.FLOAT, depths)
+	return depths
+}
+
+// StencilPixels returns the stencil values of a sub-region of the Frame as tightly packed rows
+// starting from the bottom one (or the top one, see SetConvention).
+//
+// The Frame must have a stencil buffer, i.e. a depth buffer in the Depth24Stencil8 format, and it
+// must not be multisampled. Like Texture.Pixels, this stalls until the GPU has finished all the
+// drawing before it.
+func (f *Frame) StencilPixels(x, y, w, h int) []uint8 {
+	if f.samples > 0 {
+		panic("stencil pixels: multisampled frame, use Resolve")
+	}
+	hasStencil := f.isDefault ||
+		f.depth != nil && f.depth.format.HasStencil() ||
+		f.depthRb != nil && f.depthRb.format.HasStencil()
+	if !hasStencil {
+		panic("stencil pixels: frame without stencil buffer")
+	}
+	stencils := make([]uint8, w*h)
+	readFramePixels(f, x, y, w, h, gl.STENCIL_INDEX, gl.UNSIGNED_BYTE, stencils)
+	return stencils
+}
+
+// readFramePixels reads a sub-region of the Frame in the given pixel format and type into data,
+// one element per pixel.
+func readFramePixels[T Element](f *Frame, x, y, w, h int, format, xtype uint32, data []T) {
+	if len(data) == 0 {
+		return
+	}
+	f.rf.obj = f.fb.obj
+	f.rf.bind()
+	defer f.rf.restore()
+	defer pixelStore(true, 1, 0)()
+	gl.ReadPixels(int32(x), int32(flipY(y, h, f.height)), int32(w), int32(h), format, xtype, gl.Ptr(&data[0]))
+	flipRows(data, w)
+}
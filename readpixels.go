@@ -0,0 +1,156 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// PixelReadback is an asynchronous readback of pixels started by Frame.BeginReadPixels or
+// Texture.BeginReadPixels.
+//
+// Reading pixels directly (e.g. by Texture.Pixels) stalls until the GPU has finished all the
+// drawing before it. A PixelReadback instead copies the pixels into a pixel pack buffer in the
+// background, so they can be harvested a frame or two later, e.g. for screenshots or GPU picking,
+// without hitches.
+type PixelReadback struct {
+	pbo     binder
+	fence   uintptr
+	size    int
+	rowSize int                   // in bytes
+	crop    func([]uint8) []uint8 // extracts the requested sub-region, if not read directly
+}
+
+// beginReadPixels starts reading size bytes of pixels into a new pixel pack buffer using the read
+// function, which gets the buffer bound.
+func beginReadPixels(size, rowSize int, read func()) *PixelReadback {
+	r := &PixelReadback{
+		pbo: binder{
+			restoreLoc: gl.PIXEL_PACK_BUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(gl.PIXEL_PACK_BUFFER, obj)
+			},
+		},
+		size:    size,
+		rowSize: rowSize,
+	}
+
+	gl.GenBuffers(1, &r.pbo.obj)
+
+	r.pbo.bind()
+	if size > 0 {
+		gl.BufferData(gl.PIXEL_PACK_BUFFER, size, nil, gl.STREAM_READ)
+		restore := pixelStore(true, 1, 0)
+		read()
+		restore()
+	}
+	r.pbo.restore()
+
+	r.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+
+	runtime.SetFinalizer(r, (*PixelReadback).delete)
+
+	return r
+}
+
+// BeginReadPixels starts reading the content of a sub-region of the first color attachment of the
+// Frame asynchronously. The pixels are in the format of the attachment (see ColorFormat), see
+// FinishReadPixels.
+func (f *Frame) BeginReadPixels(x, y, w, h int) *PixelReadback {
+	if f.samples > 0 {
+		panic("begin read pixels: multisampled frame, use Resolve")
+	}
+	if f.ColorAttachments() == 0 {
+		panic("begin read pixels: frame without color attachments")
+	}
+	format := f.ColorFormat()
+	bpp := format.BytesPerPixel()
+	y = flipY(y, h, f.height)
+	f.rf.obj = f.fb.obj
+	f.rf.bind()
+	defer f.rf.restore()
+	return beginReadPixels(w*h*bpp, w*bpp, func() {
+		if f.isDefault {
+			gl.ReadBuffer(gl.BACK)
+		} else {
+			gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+		}
+		gl.ReadPixels(int32(x), int32(y), int32(w), int32(h), format.pixelFormat(), format.pixelType(), gl.PtrOffset(0))
+	})
+}
+
+// BeginReadPixels starts reading the content of a sub-region of the Texture asynchronously. The
+// pixels are in the format of the Texture, see FinishReadPixels.
+//
+// The whole Texture is transferred, only the sub-region is extracted by FinishReadPixels.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) BeginReadPixels(x, y, w, h int) *PixelReadback {
+	bpp := t.format.BytesPerPixel()
+	y = flipY(y, h, t.height)
+	r := beginReadPixels(t.width*t.height*bpp, w*bpp, func() {
+		gl.GetTexImage(gl.TEXTURE_2D, 0, t.format.pixelFormat(), t.format.pixelType(), gl.PtrOffset(0))
+	})
+	width := t.width
+	r.crop = func(pixels []uint8) []uint8 {
+		subPixels := make([]uint8, w*h*bpp)
+		for i := 0; i < h; i++ {
+			copy(subPixels[i*w*bpp:(i+1)*w*bpp], pixels[((i+y)*width+x)*bpp:])
+		}
+		return subPixels
+	}
+	return r
+}
+
+func (r *PixelReadback) delete() {
+	mainthread.CallNonBlock(r.release)
+}
+
+func (r *PixelReadback) release() {
+	if r.fence != 0 {
+		gl.DeleteSync(r.fence)
+		r.fence = 0
+	}
+	gl.DeleteBuffers(1, &r.pbo.obj)
+	r.pbo.invalidate()
+}
+
+// Ready returns whether the pixels have arrived, i.e. whether FinishReadPixels would return
+// without waiting.
+func (r *PixelReadback) Ready() bool {
+	if r.fence == 0 {
+		return true
+	}
+	status := gl.ClientWaitSync(r.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 0)
+	return status != gl.TIMEOUT_EXPIRED
+}
+
+// FinishReadPixels returns the pixels as a sequence of tightly packed rows, starting from the
+// bottom one (or the top one, see SetConvention), and frees the readback buffer. If the pixels
+// haven't arrived yet, it waits for them. It must be called only once.
+func (r *PixelReadback) FinishReadPixels() []uint8 {
+	if r.fence != 0 {
+		for {
+			status := gl.ClientWaitSync(r.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+			if status != gl.TIMEOUT_EXPIRED {
+				break
+			}
+		}
+	}
+
+	pixels := make([]uint8, r.size)
+	if r.size > 0 {
+		r.pbo.bind()
+		gl.GetBufferSubData(gl.PIXEL_PACK_BUFFER, 0, r.size, gl.Ptr(pixels))
+		r.pbo.restore()
+	}
+	runtime.SetFinalizer(r, nil)
+	r.release()
+
+	if r.crop != nil {
+		pixels = r.crop(pixels)
+	}
+	flipRows(pixels, r.rowSize)
+	return pixels
+}
@@ -0,0 +1,145 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// Renderbuffer is an OpenGL renderbuffer, an attachment of a Frame that can't be sampled. It's
+// either a depth (and stencil) buffer, or a color buffer (see NewColorRenderbuffer). When the
+// depths are only needed for depth testing while drawing, or the colors are only resolved or
+// blitted, it's cheaper than a DepthTexture or a MultisampleTexture on many drivers. It can be
+// multisampled, for attaching to multisampled Frames.
+type Renderbuffer struct {
+	rb            binder
+	width, height int
+	samples       int
+	format        DepthFormat
+	color         bool // whether it's a color buffer with colorFormat instead of format
+	colorFormat   TextureFormat
+}
+
+// NewRenderbuffer creates a new renderbuffer with the specified width, height, number of samples
+// per pixel (0 if not multisampled) and depth format. The content of the renderbuffer is undefined
+// until it's drawn into or cleared.
+//
+// An error is returned if the number of samples is larger than MaxSamples.
+func NewRenderbuffer(width, height, samples int, format DepthFormat) (*Renderbuffer, error) {
+	return newRenderbuffer(width, height, samples, &Renderbuffer{format: format})
+}
+
+// NewColorRenderbuffer creates a new color renderbuffer with the specified width, height, number
+// of samples per pixel (0 if not multisampled) and pixel format. The content of the renderbuffer
+// is undefined until it's drawn into or cleared. See NewMultisampleRenderbufferFrame for a Frame
+// with color Renderbuffers.
+//
+// An error is returned if the number of samples is larger than MaxSamples.
+func NewColorRenderbuffer(width, height, samples int, format TextureFormat) (*Renderbuffer, error) {
+	return newRenderbuffer(width, height, samples, &Renderbuffer{color: true, colorFormat: format})
+}
+
+// newRenderbuffer creates the renderbuffer rb, which has only the format filled in.
+func newRenderbuffer(width, height, samples int, rb *Renderbuffer) (*Renderbuffer, error) {
+	if samples < 0 || samples > MaxSamples() {
+		return nil, errors.Errorf("failed to create renderbuffer: %d samples not supported", samples)
+	}
+	if err := checkTextureSize(width, height); err != nil {
+		return nil, errors.Wrap(err, "failed to create renderbuffer")
+	}
+
+	rb.rb = binder{
+		restoreLoc: gl.RENDERBUFFER_BINDING,
+		bindFunc: func(obj uint32) {
+			gl.BindRenderbuffer(gl.RENDERBUFFER, obj)
+		},
+	}
+	rb.width, rb.height, rb.samples = width, height, samples
+
+	gl.GenRenderbuffers(1, &rb.rb.obj)
+
+	rb.Resize(width, height)
+
+	runtime.SetFinalizer(rb, (*Renderbuffer).delete)
+
+	return rb, nil
+}
+
+// Resize reallocates the storage of the Renderbuffer with the new width and height, keeping its
+// OpenGL ID. The content is undefined afterwards.
+func (rb *Renderbuffer) Resize(width, height int) {
+	rb.width, rb.height = width, height
+	internalFormat := rb.format.internalFormat()
+	if rb.color {
+		internalFormat = rb.colorFormat.internalFormat()
+	}
+	rb.rb.bind()
+	gl.RenderbufferStorageMultisample(
+		gl.RENDERBUFFER,
+		int32(rb.samples),
+		uint32(internalFormat),
+		int32(width),
+		int32(height),
+	)
+	rb.rb.restore()
+}
+
+func (rb *Renderbuffer) delete() {
+	mainthread.CallNonBlock(rb.release)
+}
+
+// Delete deletes the Renderbuffer immediately instead of waiting for the garbage collector. The
+// Renderbuffer must not be used afterwards. Deleting a Renderbuffer multiple times is harmless.
+func (rb *Renderbuffer) Delete() {
+	runtime.SetFinalizer(rb, nil)
+	rb.release()
+}
+
+func (rb *Renderbuffer) release() {
+	gl.DeleteRenderbuffers(1, &rb.rb.obj)
+	rb.rb.invalidate()
+}
+
+// ID returns the OpenGL ID of this Renderbuffer.
+func (rb *Renderbuffer) ID() uint32 {
+	return rb.rb.obj
+}
+
+// Width returns the width of the Renderbuffer in pixels.
+func (rb *Renderbuffer) Width() int {
+	return rb.width
+}
+
+// Height returns the height of the Renderbuffer in pixels.
+func (rb *Renderbuffer) Height() int {
+	return rb.height
+}
+
+// Samples returns the number of samples per pixel of the Renderbuffer, or 0 if it's not
+// multisampled.
+func (rb *Renderbuffer) Samples() int {
+	return rb.samples
+}
+
+// Color returns whether the Renderbuffer is a color buffer, see NewColorRenderbuffer.
+func (rb *Renderbuffer) Color() bool {
+	return rb.color
+}
+
+// Format returns the depth format of a depth Renderbuffer.
+func (rb *Renderbuffer) Format() DepthFormat {
+	if rb.color {
+		panic("format: color renderbuffer, use ColorFormat")
+	}
+	return rb.format
+}
+
+// ColorFormat returns the pixel format of a color Renderbuffer.
+func (rb *Renderbuffer) ColorFormat() TextureFormat {
+	if !rb.color {
+		panic("color format: depth renderbuffer, use Format")
+	}
+	return rb.colorFormat
+}
@@ -0,0 +1,89 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// RingVertexSlice is a set of N VertexSlices used in rotation, one per frame, for vertex data that
+// changes every frame.
+//
+// Writing to a buffer the GPU is still reading from makes the driver either stall or make a copy.
+// With a RingVertexSlice, this frame's data goes to a different buffer than the last frame's, so
+// the write only waits if the GPU is more than N-1 frames behind. Three buffers are usually
+// enough.
+type RingVertexSlice struct {
+	slices []*VertexSlice
+	fences []uintptr
+	cur    int
+}
+
+// MakeRingVertexSlice creates a new RingVertexSlice of n VertexSlices, each with the specified
+// len and cap (see MakeVertexSlice).
+func MakeRingVertexSlice(shader *Shader, n, len, cap int) *RingVertexSlice {
+	if n <= 0 {
+		panic("failed to make ring vertex slice: n must be positive")
+	}
+	r := &RingVertexSlice{
+		slices: make([]*VertexSlice, n),
+		fences: make([]uintptr, n),
+	}
+	for i := range r.slices {
+		r.slices[i] = MakeVertexSliceUsage(shader, len, cap, StreamDraw)
+	}
+
+	runtime.SetFinalizer(r, (*RingVertexSlice).delete)
+
+	return r
+}
+
+func (r *RingVertexSlice) delete() {
+	fences := r.fences
+	mainthread.CallNonBlock(func() {
+		for _, fence := range fences {
+			if fence != 0 {
+				gl.DeleteSync(fence)
+			}
+		}
+	})
+}
+
+// N returns the number of VertexSlices in the RingVertexSlice.
+func (r *RingVertexSlice) N() int {
+	return len(r.slices)
+}
+
+// Current returns the VertexSlice for the current frame.
+func (r *RingVertexSlice) Current() *VertexSlice {
+	return r.slices[r.cur]
+}
+
+// Next marks the end of the use of the current VertexSlice and returns the next one. Call it once
+// per frame, before writing the frame's vertex data, and draw the returned VertexSlice in that
+// frame.
+//
+// If the GPU still reads from the returned VertexSlice (i.e. it's more than N-1 frames behind),
+// this method waits until it's done.
+func (r *RingVertexSlice) Next() *VertexSlice {
+	if r.fences[r.cur] != 0 {
+		gl.DeleteSync(r.fences[r.cur])
+	}
+	r.fences[r.cur] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+
+	r.cur = (r.cur + 1) % len(r.slices)
+
+	if fence := r.fences[r.cur]; fence != 0 {
+		for {
+			status := gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+			if status != gl.TIMEOUT_EXPIRED {
+				break
+			}
+		}
+		gl.DeleteSync(fence)
+		r.fences[r.cur] = 0
+	}
+
+	return r.slices[r.cur]
+}
@@ -0,0 +1,48 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// ContextLostError is returned by CheckContext when the OpenGL context has been lost, e.g.
+// because the GPU was reset or the driver was updated. All OpenGL objects of a lost context are
+// gone, the only way to recover is to create a new context, call Init and recreate everything.
+type ContextLostError struct {
+	// Guilty is true if the reset was caused by this context, e.g. by a shader that ran for too
+	// long, and false if it was caused by something else or the cause is unknown.
+	Guilty bool
+}
+
+func (err *ContextLostError) Error() string {
+	if err.Guilty {
+		return "OpenGL context lost: reset caused by this context"
+	}
+	return "OpenGL context lost"
+}
+
+// CheckContext returns a *ContextLostError if the current OpenGL context has been lost, and nil
+// otherwise. It's cheap enough to be called once per frame.
+//
+// Context loss is only reported if the context was created with a reset notification strategy
+// of "lose context on reset" (e.g. the glfw.ContextRobustness window hint set to
+// glfw.LoseContextOnReset) and the driver supports OpenGL 4.5 or one of the
+// GL_ARB_robustness and GL_KHR_robustness extensions. Otherwise, this function always returns
+// nil.
+func CheckContext() error {
+	var status uint32
+	switch {
+	case versionAtLeast(4, 5) || hasExtension("GL_KHR_robustness"):
+		status = gl.GetGraphicsResetStatus()
+	case hasExtension("GL_ARB_robustness"):
+		status = gl.GetGraphicsResetStatusARB()
+	default:
+		return nil
+	}
+
+	switch status {
+	case gl.NO_ERROR:
+		return nil
+	case gl.GUILTY_CONTEXT_RESET:
+		return &ContextLostError{Guilty: true}
+	default:
+		return &ContextLostError{Guilty: false}
+	}
+}
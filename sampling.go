@@ -0,0 +1,67 @@
+package glhf
+
+import "github.com/go-gl/gl/v3.3-core/gl"
+
+// SamplingPreset is a named bundle of sampling parameters of a Texture: filtering, wrapping,
+// mipmap level-of-detail bias and anisotropy. Using presets keeps the sampling consistent across
+// an app without repeating the parameters at each call site.
+type SamplingPreset int
+
+// List of all sampling presets.
+const (
+	// UISampling is for interface elements drawn at (about) their native size: smooth filtering,
+	// clamped to the edge.
+	UISampling SamplingPreset = iota
+
+	// PixelArtSampling is for pixel art scaled by integer factors: pixely filtering, clamped to
+	// the edge.
+	PixelArtSampling
+
+	// WorldSampling is for textures of the game world, seen at all distances and angles: smooth
+	// filtering with mipmaps (if the Texture has them), repeating, with the maximum anisotropy
+	// supported (up to 16).
+	WorldSampling
+
+	// TextSampling is for glyph textures: smooth filtering, clamped to the edge, with a negative
+	// mipmap level-of-detail bias that keeps minified text sharp.
+	TextSampling
+)
+
+// SetSampling sets all sampling parameters of the Texture according to the preset. This
+// overrides the smoothness set by SetSmooth.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetSampling(preset SamplingPreset) {
+	var (
+		smooth     bool
+		wrap       int32
+		lodBias    float32
+		anisotropy float32 = 1
+	)
+	switch preset {
+	case UISampling:
+		smooth, wrap = true, gl.CLAMP_TO_EDGE
+	case PixelArtSampling:
+		smooth, wrap = false, gl.CLAMP_TO_EDGE
+	case WorldSampling:
+		smooth, wrap = true, gl.REPEAT
+		if anisotropySupported() {
+			gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY, &anisotropy)
+			if anisotropy > 16 {
+				anisotropy = 16
+			}
+		}
+	case TextSampling:
+		smooth, wrap, lodBias = true, gl.CLAMP_TO_EDGE, -0.5
+	default:
+		panic("set sampling: invalid preset")
+	}
+
+	t.SetSmooth(smooth)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, wrap)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, wrap)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_LOD_BIAS, lodBias)
+	if anisotropySupported() {
+		gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY, anisotropy)
+	}
+}
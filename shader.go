@@ -7,6 +7,7 @@ import (
 	"github.com/faiface/mainthread"
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/pkg/errors"
 )
 
 // Shader is an OpenGL shader program.
@@ -15,6 +16,8 @@ type Shader struct {
 	vertexFmt  AttrFormat
 	uniformFmt AttrFormat
 	uniformLoc []int32
+
+	placeholder bool // shared, see ErrorShader
 }
 
 // NewShader creates a new shader program from the specified vertex shader and fragment shader
@@ -22,7 +25,19 @@ type Shader struct {
 //
 // Note that vertexShader and fragmentShader parameters must contain the source code, they're
 // not filenames.
+//
+// With the Resilient failure policy, a failure is logged and ErrorShader is returned instead of an
+// error.
 func NewShader(vertexFmt, uniformFmt AttrFormat, vertexShader, fragmentShader string) (*Shader, error) {
+	shader, err := newShader(vertexFmt, uniformFmt, vertexShader, fragmentShader)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return ErrorShader(vertexFmt, uniformFmt), nil
+	}
+	return shader, err
+}
+
+func newShader(vertexFmt, uniformFmt AttrFormat, vertexShader, fragmentShader string) (*Shader, error) {
 	shader := &Shader{
 		program: binder{
 			restoreLoc: gl.CURRENT_PROGRAM,
@@ -35,70 +50,26 @@ func NewShader(vertexFmt, uniformFmt AttrFormat, vertexShader, fragmentShader st
 		uniformLoc: make([]int32, len(uniformFmt)),
 	}
 
-	var vshader, fshader uint32
-
-	// vertex shader
-	{
-		vshader = gl.CreateShader(gl.VERTEX_SHADER)
-		src, free := gl.Strs(vertexShader)
-		defer free()
-		length := int32(len(vertexShader))
-		gl.ShaderSource(vshader, 1, src, &length)
-		gl.CompileShader(vshader)
-
-		var success int32
-		gl.GetShaderiv(vshader, gl.COMPILE_STATUS, &success)
-		if success == gl.FALSE {
-			var logLen int32
-			gl.GetShaderiv(vshader, gl.INFO_LOG_LENGTH, &logLen)
-
-			infoLog := make([]byte, logLen)
-			gl.GetShaderInfoLog(vshader, logLen, nil, &infoLog[0])
-			return nil, fmt.Errorf("error compiling vertex shader: %s", string(infoLog))
-		}
-
-		defer gl.DeleteShader(vshader)
+	vshader, err := compileShader(gl.VERTEX_SHADER, vertexShader)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling vertex shader: %v", err)
 	}
+	defer gl.DeleteShader(vshader)
 
-	// fragment shader
-	{
-		fshader = gl.CreateShader(gl.FRAGMENT_SHADER)
-		src, free := gl.Strs(fragmentShader)
-		defer free()
-		length := int32(len(fragmentShader))
-		gl.ShaderSource(fshader, 1, src, &length)
-		gl.CompileShader(fshader)
-
-		var success int32
-		gl.GetShaderiv(fshader, gl.COMPILE_STATUS, &success)
-		if success == gl.FALSE {
-			var logLen int32
-			gl.GetShaderiv(fshader, gl.INFO_LOG_LENGTH, &logLen)
-
-			infoLog := make([]byte, logLen)
-			gl.GetShaderInfoLog(fshader, logLen, nil, &infoLog[0])
-			return nil, fmt.Errorf("error compiling fragment shader: %s", string(infoLog))
-		}
-
-		defer gl.DeleteShader(fshader)
+	fshader, err := compileShader(gl.FRAGMENT_SHADER, fragmentShader)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling fragment shader: %v", err)
 	}
+	defer gl.DeleteShader(fshader)
 
 	// shader program
 	{
 		shader.program.obj = gl.CreateProgram()
 		gl.AttachShader(shader.program.obj, vshader)
 		gl.AttachShader(shader.program.obj, fshader)
-		gl.LinkProgram(shader.program.obj)
-
-		var success int32
-		gl.GetProgramiv(shader.program.obj, gl.LINK_STATUS, &success)
-		if success == gl.FALSE {
-			var logLen int32
-			gl.GetProgramiv(shader.program.obj, gl.INFO_LOG_LENGTH, &logLen)
-
-			infoLog := make([]byte, logLen)
-			gl.GetProgramInfoLog(shader.program.obj, logLen, nil, &infoLog[0])
-			return nil, fmt.Errorf("error linking shader program: %s", string(infoLog))
+		err := linkProgram(shader.program.obj)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -114,9 +85,23 @@ func NewShader(vertexFmt, uniformFmt AttrFormat, vertexShader, fragmentShader st
 }
 
 func (s *Shader) delete() {
-	mainthread.CallNonBlock(func() {
-		gl.DeleteProgram(s.program.obj)
-	})
+	mainthread.CallNonBlock(s.release)
+}
+
+// Delete deletes the Shader immediately instead of waiting for the garbage collector. The Shader
+// must not be used afterwards. Deleting a Shader multiple times is harmless. Deleting the shared
+// ErrorShader does nothing.
+func (s *Shader) Delete() {
+	if s.placeholder {
+		return
+	}
+	runtime.SetFinalizer(s, nil)
+	s.release()
+}
+
+func (s *Shader) release() {
+	gl.DeleteProgram(s.program.obj)
+	s.program.invalidate()
 }
 
 // ID returns the OpenGL ID of this Shader.
@@ -155,6 +140,13 @@ func (s *Shader) UniformFormat() AttrFormat {
 //   Attr{Type: Mat4}:  mgl32.Mat4
 //   Attr{Type: Mat42}: mgl32.Mat4x2
 //   Attr{Type: Mat43}: mgl32.Mat4x3
+//   Attr{Type: Uint}:  uint32
+//   Attr{Type: IVec2}: [2]int32
+//   Attr{Type: IVec3}: [3]int32
+//   Attr{Type: IVec4}: [4]int32
+//   Attr{Type: UVec2}: [2]uint32
+//   Attr{Type: UVec3}: [3]uint32
+//   Attr{Type: UVec4}: [4]uint32
 // No other types are supported.
 //
 // The Shader must be bound before calling this method.
@@ -163,62 +155,132 @@ func (s *Shader) SetUniformAttr(uniform int, value interface{}) (ok bool) {
 		return false
 	}
 
-	switch s.uniformFmt[uniform].Type {
+	setUniformAttr(s.uniformLoc[uniform], s.uniformFmt[uniform].Type, value)
+
+	return true
+}
+
+// Begin binds the Shader program. This is necessary before using the Shader.
+func (s *Shader) Begin() {
+	s.program.bind()
+}
+
+// End unbinds the Shader program and restores the previous one.
+func (s *Shader) End() {
+	s.program.restore()
+}
+
+// compileShader compiles a single shader stage of the given type and returns its OpenGL ID. On
+// failure, the returned error contains the info log of the compilation.
+func compileShader(xtype uint32, source string) (uint32, error) {
+	shader := gl.CreateShader(xtype)
+	src, free := gl.Strs(source)
+	defer free()
+	length := int32(len(source))
+	gl.ShaderSource(shader, 1, src, &length)
+	gl.CompileShader(shader)
+
+	var success int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &success)
+	if success == gl.FALSE {
+		var logLen int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLen)
+
+		infoLog := make([]byte, logLen)
+		gl.GetShaderInfoLog(shader, logLen, nil, &infoLog[0])
+		gl.DeleteShader(shader)
+		return 0, errors.New(string(infoLog))
+	}
+
+	return shader, nil
+}
+
+// linkProgram links a program with already attached shaders.
+func linkProgram(program uint32) error {
+	gl.LinkProgram(program)
+
+	var success int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &success)
+	if success == gl.FALSE {
+		var logLen int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLen)
+
+		infoLog := make([]byte, logLen)
+		gl.GetProgramInfoLog(program, logLen, nil, &infoLog[0])
+		return fmt.Errorf("error linking shader program: %s", string(infoLog))
+	}
+
+	return nil
+}
+
+// setUniformAttr sets the value of a uniform of the given type at the given location of the
+// currently bound program. See (*Shader).SetUniformAttr for the accepted value types.
+func setUniformAttr(loc int32, typ AttrType, value interface{}) {
+	switch typ {
 	case Int:
 		value := value.(int32)
-		gl.Uniform1iv(s.uniformLoc[uniform], 1, &value)
+		gl.Uniform1iv(loc, 1, &value)
 	case Float:
 		value := value.(float32)
-		gl.Uniform1fv(s.uniformLoc[uniform], 1, &value)
+		gl.Uniform1fv(loc, 1, &value)
 	case Vec2:
 		value := value.(mgl32.Vec2)
-		gl.Uniform2fv(s.uniformLoc[uniform], 1, &value[0])
+		gl.Uniform2fv(loc, 1, &value[0])
 	case Vec3:
 		value := value.(mgl32.Vec3)
-		gl.Uniform3fv(s.uniformLoc[uniform], 1, &value[0])
+		gl.Uniform3fv(loc, 1, &value[0])
 	case Vec4:
 		value := value.(mgl32.Vec4)
-		gl.Uniform4fv(s.uniformLoc[uniform], 1, &value[0])
+		gl.Uniform4fv(loc, 1, &value[0])
 	case Mat2:
 		value := value.(mgl32.Mat2)
-		gl.UniformMatrix2fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix2fv(loc, 1, false, &value[0])
 	case Mat23:
 		value := value.(mgl32.Mat2x3)
-		gl.UniformMatrix2x3fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix2x3fv(loc, 1, false, &value[0])
 	case Mat24:
 		value := value.(mgl32.Mat2x4)
-		gl.UniformMatrix2x4fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix2x4fv(loc, 1, false, &value[0])
 	case Mat3:
 		value := value.(mgl32.Mat3)
-		gl.UniformMatrix3fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix3fv(loc, 1, false, &value[0])
 	case Mat32:
 		value := value.(mgl32.Mat3x2)
-		gl.UniformMatrix3x2fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix3x2fv(loc, 1, false, &value[0])
 	case Mat34:
 		value := value.(mgl32.Mat3x4)
-		gl.UniformMatrix3x4fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix3x4fv(loc, 1, false, &value[0])
 	case Mat4:
 		value := value.(mgl32.Mat4)
-		gl.UniformMatrix4fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix4fv(loc, 1, false, &value[0])
 	case Mat42:
 		value := value.(mgl32.Mat4x2)
-		gl.UniformMatrix4x2fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix4x2fv(loc, 1, false, &value[0])
 	case Mat43:
 		value := value.(mgl32.Mat4x3)
-		gl.UniformMatrix4x3fv(s.uniformLoc[uniform], 1, false, &value[0])
+		gl.UniformMatrix4x3fv(loc, 1, false, &value[0])
+	case Uint:
+		value := value.(uint32)
+		gl.Uniform1uiv(loc, 1, &value)
+	case IVec2:
+		value := value.([2]int32)
+		gl.Uniform2iv(loc, 1, &value[0])
+	case IVec3:
+		value := value.([3]int32)
+		gl.Uniform3iv(loc, 1, &value[0])
+	case IVec4:
+		value := value.([4]int32)
+		gl.Uniform4iv(loc, 1, &value[0])
+	case UVec2:
+		value := value.([2]uint32)
+		gl.Uniform2uiv(loc, 1, &value[0])
+	case UVec3:
+		value := value.([3]uint32)
+		gl.Uniform3uiv(loc, 1, &value[0])
+	case UVec4:
+		value := value.([4]uint32)
+		gl.Uniform4uiv(loc, 1, &value[0])
 	default:
 		panic("set uniform attr: invalid attribute type")
 	}
-
-	return true
-}
-
-// Begin binds the Shader program. This is necessary before using the Shader.
-func (s *Shader) Begin() {
-	s.program.bind()
-}
-
-// End unbinds the Shader program and restores the previous one.
-func (s *Shader) End() {
-	s.program.restore()
 }
@@ -0,0 +1,128 @@
+package glhf
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LoadShaderFS is like NewShader, except that the vertex and fragment shader sources are read
+// from the files vertexPath and fragmentPath in fsys, e.g. an embed.FS. The sources are
+// preprocessed by ReadShaderFS, so they may use #include directives and the defines.
+//
+// With the Resilient failure policy, a failure is logged and ErrorShader is returned instead of an
+// error.
+func LoadShaderFS(fsys fs.FS, vertexFmt, uniformFmt AttrFormat, vertexPath, fragmentPath string, defines map[string]string) (*Shader, error) {
+	shader, err := loadShaderFS(fsys, vertexFmt, uniformFmt, vertexPath, fragmentPath, defines)
+	if err != nil && failurePolicy == Resilient {
+		logf("%v", err)
+		return ErrorShader(vertexFmt, uniformFmt), nil
+	}
+	return shader, err
+}
+
+func loadShaderFS(fsys fs.FS, vertexFmt, uniformFmt AttrFormat, vertexPath, fragmentPath string, defines map[string]string) (*Shader, error) {
+	vertexShader, err := ReadShaderFS(fsys, vertexPath, defines)
+	if err != nil {
+		return nil, err
+	}
+	fragmentShader, err := ReadShaderFS(fsys, fragmentPath, defines)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := newShader(vertexFmt, uniformFmt, vertexShader, fragmentShader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load shader %s, %s", vertexPath, fragmentPath)
+	}
+	return shader, nil
+}
+
+// ReadShaderFS reads the shader source from the file name in fsys and preprocesses it:
+//
+// Each line of the form
+//   #include "other.glsl"
+// is replaced by the (also preprocessed) contents of the named file. Relative names are resolved
+// against the directory of the including file. Including a file that is already being included
+// is an error.
+//
+// Each define is inserted as
+//   #define NAME VALUE
+// right after the #version directive (or at the very beginning, if there's none), in the order
+// of the names.
+func ReadShaderFS(fsys fs.FS, name string, defines map[string]string) (string, error) {
+	source, err := readShaderInclude(fsys, name, map[string]bool{})
+	if err != nil {
+		return "", err
+	}
+	return insertDefines(source, defines), nil
+}
+
+// insertDefines inserts the defines right after the #version directive of the source, in the
+// order of the names.
+func insertDefines(source string, defines map[string]string) string {
+	if len(defines) == 0 {
+		return source
+	}
+
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var defs strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&defs, "#define %s %s\n", name, defines[name])
+	}
+
+	// the #version directive must come first
+	lines := strings.SplitAfter(source, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#version") {
+			if !strings.HasSuffix(line, "\n") {
+				lines[i] += "\n"
+			}
+			lines[i] += defs.String()
+			return strings.Join(lines, "")
+		}
+	}
+	return defs.String() + source
+}
+
+func readShaderInclude(fsys fs.FS, name string, including map[string]bool) (string, error) {
+	if including[name] {
+		return "", errors.Errorf("failed to read shader %s: recursive #include", name)
+	}
+	including[name] = true
+	defer delete(including, name)
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read shader")
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#include") {
+			continue
+		}
+		arg := strings.TrimSpace(strings.TrimPrefix(trimmed, "#include"))
+		if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+			return "", errors.Errorf("failed to read shader %s: malformed #include on line %d", name, i+1)
+		}
+		included, err := readShaderInclude(fsys, path.Join(path.Dir(name), arg[1:len(arg)-1]), including)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(included, "\n") {
+			included += "\n"
+		}
+		lines[i] = included
+	}
+	return strings.Join(lines, ""), nil
+}
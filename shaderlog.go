@@ -0,0 +1,228 @@
+package glhf
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// ShaderLog is a "printf" for shaders: a buffer that shaders append records of values to, and
+// which is read back and decoded on the CPU, e.g. once per frame. It's a way to debug the logic
+// of a shader without stepping through it in a GPU debugger.
+//
+// Include the declarations returned by ShaderLogGLSL(log.Binding()) in the shader, bind the
+// ShaderLog by BindBase before drawing or dispatching, and call glhfLog in the shader:
+//   glhfLog(7u, vec4(position, depth));
+// Each record carries a tag (to tell the call sites apart) and up to four values.
+//
+// The ShaderLog uses shader storage buffers, so it requires OpenGL 4.3.
+type ShaderLog struct {
+	buf     binder
+	cap     int
+	binding int // acquired shader storage buffer binding point
+}
+
+// ShaderLogRecord is a single record written by glhfLog.
+type ShaderLogRecord struct {
+	Tag    uint32
+	Kind   AttrType  // Vec4, IVec4 or UVec4, depending on the overload of glhfLog
+	Values [4]uint32 // raw bits of the values
+}
+
+// Floats returns the values of the record interpreted as floats.
+func (r ShaderLogRecord) Floats() [4]float32 {
+	var f [4]float32
+	for i, v := range r.Values {
+		f[i] = math.Float32frombits(v)
+	}
+	return f
+}
+
+// Ints returns the values of the record interpreted as signed integers.
+func (r ShaderLogRecord) Ints() [4]int32 {
+	var n [4]int32
+	for i, v := range r.Values {
+		n[i] = int32(v)
+	}
+	return n
+}
+
+// String returns the record formatted according to its kind.
+func (r ShaderLogRecord) String() string {
+	switch r.Kind {
+	case IVec4:
+		return fmt.Sprintf("[%d] %v", r.Tag, r.Ints())
+	case UVec4:
+		return fmt.Sprintf("[%d] %v", r.Tag, r.Values)
+	}
+	return fmt.Sprintf("[%d] %v", r.Tag, r.Floats())
+}
+
+// shaderLogRecordWords is the size of a record in 32-bit words: tag, kind and four values.
+const shaderLogRecordWords = 6
+
+// NewShaderLog creates a new ShaderLog with room for cap records between two calls to Records.
+// Records written after the ShaderLog is full are dropped.
+//
+// The ShaderLog acquires its own shader storage buffer binding point, see AcquireBinding and
+// Binding.
+func NewShaderLog(cap int) (*ShaderLog, error) {
+	if !versionAtLeast(4, 3) && !hasExtension("GL_ARB_shader_storage_buffer_object") {
+		return nil, errors.New("failed to create shader log: shader storage buffers not supported")
+	}
+	binding, err := AcquireBinding(StorageBufferBinding)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create shader log")
+	}
+
+	l := &ShaderLog{
+		buf: binder{
+			restoreLoc: gl.SHADER_STORAGE_BUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, obj)
+			},
+		},
+		cap:     cap,
+		binding: binding,
+	}
+
+	gl.GenBuffers(1, &l.buf.obj)
+
+	l.buf.bind()
+	emptyData := make([]byte, (1+cap*shaderLogRecordWords)*4)
+	gl.BufferData(gl.SHADER_STORAGE_BUFFER, len(emptyData), gl.Ptr(emptyData), gl.DYNAMIC_READ)
+	l.buf.restore()
+
+	runtime.SetFinalizer(l, (*ShaderLog).delete)
+
+	return l, nil
+}
+
+func (l *ShaderLog) delete() {
+	mainthread.CallNonBlock(l.release)
+}
+
+// Delete deletes the ShaderLog immediately instead of waiting for the garbage collector and
+// releases its binding point. The ShaderLog must not be used afterwards. Deleting a ShaderLog
+// multiple times is harmless.
+func (l *ShaderLog) Delete() {
+	runtime.SetFinalizer(l, nil)
+	l.release()
+}
+
+func (l *ShaderLog) release() {
+	if l.buf.obj == 0 {
+		// already deleted, don't release the binding point twice
+		return
+	}
+	gl.DeleteBuffers(1, &l.buf.obj)
+	l.buf.invalidate()
+	ReleaseBinding(StorageBufferBinding, l.binding)
+}
+
+// ID returns the OpenGL ID of the buffer of this ShaderLog.
+func (l *ShaderLog) ID() uint32 {
+	return l.buf.obj
+}
+
+// Binding returns the shader storage buffer binding point of the ShaderLog, which is to be passed
+// to ShaderLogGLSL.
+func (l *ShaderLog) Binding() int {
+	return l.binding
+}
+
+// BindBase binds the ShaderLog to its shader storage buffer binding point, see Binding.
+func (l *ShaderLog) BindBase() {
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, uint32(l.binding), l.buf.obj)
+}
+
+// Records returns all records written since the last call and empties the ShaderLog. It also
+// returns the number of records dropped because the ShaderLog was full.
+//
+// This reads the buffer back from the GPU, so it waits for all draws and dispatches writing to
+// it. Call MemoryBarrier(BufferUpdateBarrier) after compute dispatches and before this method.
+func (l *ShaderLog) Records() (records []ShaderLogRecord, dropped int) {
+	l.buf.bind()
+	defer l.buf.restore()
+
+	var count uint32
+	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 0, 4, gl.Ptr(&count))
+	n := int(count)
+	if n > l.cap {
+		n, dropped = l.cap, n-l.cap
+	}
+	if n == 0 {
+		return nil, dropped
+	}
+
+	data := make([]uint32, n*shaderLogRecordWords)
+	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 4, len(data)*4, gl.Ptr(data))
+	records = make([]ShaderLogRecord, n)
+	for i := range records {
+		w := data[i*shaderLogRecordWords : (i+1)*shaderLogRecordWords]
+		records[i] = ShaderLogRecord{
+			Tag:    w[0],
+			Kind:   Vec4,
+			Values: [4]uint32{w[2], w[3], w[4], w[5]},
+		}
+		switch w[1] {
+		case 1:
+			records[i].Kind = IVec4
+		case 2:
+			records[i].Kind = UVec4
+		}
+	}
+
+	count = 0
+	gl.BufferSubData(gl.SHADER_STORAGE_BUFFER, 0, 4, gl.Ptr(&count))
+
+	return records, dropped
+}
+
+// ShaderLogGLSL returns the GLSL declarations needed to write to a ShaderLog bound to the shader
+// storage buffer binding point index. Insert them after the #version directive. The shader must
+// be at least #version 430 (or enable GL_ARB_shader_storage_buffer_object).
+//
+// The declarations include the function glhfLog(uint tag, value) with overloads for float, vec2,
+// vec3, vec4, int, ivec2, ivec3, ivec4, uint, uvec2, uvec3 and uvec4 values. Missing components
+// are zero.
+func ShaderLogGLSL(index int) string {
+	return fmt.Sprintf(shaderLogGLSL, index)
+}
+
+const shaderLogGLSL = `
+layout(std430, binding = %d) buffer GlhfLog {
+	uint glhfLogCount;
+	uint glhfLogData[];
+};
+
+void glhfLogRecord(uint tag, uint kind, uvec4 bits) {
+	uint i = atomicAdd(glhfLogCount, 1u);
+	if (int(i) * 6 + 6 > glhfLogData.length()) {
+		return;
+	}
+	glhfLogData[i*6u+0u] = tag;
+	glhfLogData[i*6u+1u] = kind;
+	glhfLogData[i*6u+2u] = bits.x;
+	glhfLogData[i*6u+3u] = bits.y;
+	glhfLogData[i*6u+4u] = bits.z;
+	glhfLogData[i*6u+5u] = bits.w;
+}
+
+void glhfLog(uint tag, vec4 v)  { glhfLogRecord(tag, 0u, floatBitsToUint(v)); }
+void glhfLog(uint tag, vec3 v)  { glhfLog(tag, vec4(v, 0.0)); }
+void glhfLog(uint tag, vec2 v)  { glhfLog(tag, vec4(v, 0.0, 0.0)); }
+void glhfLog(uint tag, float v) { glhfLog(tag, vec4(v, 0.0, 0.0, 0.0)); }
+void glhfLog(uint tag, ivec4 v) { glhfLogRecord(tag, 1u, uvec4(v)); }
+void glhfLog(uint tag, ivec3 v) { glhfLog(tag, ivec4(v, 0)); }
+void glhfLog(uint tag, ivec2 v) { glhfLog(tag, ivec4(v, 0, 0)); }
+void glhfLog(uint tag, int v)   { glhfLog(tag, ivec4(v, 0, 0, 0)); }
+void glhfLog(uint tag, uvec4 v) { glhfLogRecord(tag, 2u, v); }
+void glhfLog(uint tag, uvec3 v) { glhfLog(tag, uvec4(v, 0u)); }
+void glhfLog(uint tag, uvec2 v) { glhfLog(tag, uvec4(v, 0u, 0u)); }
+void glhfLog(uint tag, uint v)  { glhfLog(tag, uvec4(v, 0u, 0u, 0u)); }
+`
@@ -6,18 +6,42 @@ import (
 	"github.com/faiface/mainthread"
 	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/pkg/errors"
 )
 
 // Texture is an OpenGL texture.
 type Texture struct {
 	tex           binder
 	width, height int
+	format        TextureFormat
 	smooth        bool
+	mipmapped     bool
+	compressed    bool
+	placeholder   bool // shared, see CheckerTexture
 }
 
 // NewTexture creates a new texture with the specified width and height with some initial
 // pixel values. The pixels must be a sequence of RGBA values (one byte per component).
 func NewTexture(width, height int, smooth bool, pixels []uint8) *Texture {
+	return NewTextureFormat(width, height, smooth, RGBA8, pixels)
+}
+
+// NewTextureFormat creates a new texture with the specified width, height and pixel format with
+// some initial pixel values. The pixels must be a sequence of tightly packed rows in the format,
+// no padding is necessary regardless of the width. If the pixels are nil, the content of the
+// texture is undefined.
+//
+// If the number of pixels is wrong, this function panics. With the Resilient failure policy, the
+// failure is logged and the texture is filled with a checkerboard instead.
+func NewTextureFormat(width, height int, smooth bool, format TextureFormat, pixels []uint8) *Texture {
+	if pixels != nil && len(pixels) != width*height*format.BytesPerPixel() {
+		if failurePolicy != Resilient {
+			panic("failed to create texture: wrong number of pixels")
+		}
+		logf("failed to create texture: wrong number of pixels")
+		pixels = checkerPixels(width, height, format)
+	}
+
 	tex := &Texture{
 		tex: binder{
 			restoreLoc: gl.TEXTURE_BINDING_2D,
@@ -27,6 +51,7 @@ func NewTexture(width, height int, smooth bool, pixels []uint8) *Texture {
 		},
 		width:  width,
 		height: height,
+		format: format,
 	}
 
 	gl.GenTextures(1, &tex.tex.obj)
@@ -35,17 +60,23 @@ func NewTexture(width, height int, smooth bool, pixels []uint8) *Texture {
 	defer tex.End()
 
 	// initial data
+	if pixels != nil && convention.Origin == TopLeft {
+		pixels = append([]uint8(nil), pixels...)
+		flipRows(pixels, width*format.BytesPerPixel())
+	}
+	restore := pixelStore(false, 1, 0)
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
 		0,
-		gl.RGBA,
+		format.internalFormat(),
 		int32(width),
 		int32(height),
 		0,
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
-		gl.Ptr(pixels),
+		format.pixelFormat(),
+		format.pixelType(),
+		pixelsPtr(pixels),
 	)
+	restore()
 
 	borderColor := mgl32.Vec4{0, 0, 0, 0}
 	gl.TexParameterfv(gl.TEXTURE_2D, gl.TEXTURE_BORDER_COLOR, &borderColor[0])
@@ -59,10 +90,63 @@ func NewTexture(width, height int, smooth bool, pixels []uint8) *Texture {
 	return tex
 }
 
+// TryNewTexture is like NewTextureFormat, except that it validates the arguments before allocating
+// anything and reports the problems as descriptive errors, instead of panicking or letting the
+// driver fail with GL_INVALID_VALUE later. This is useful when the size comes from user input or
+// from a file.
+//
+// Init must be called before this function.
+func TryNewTexture(width, height int, smooth bool, format TextureFormat, pixels []uint8) (*Texture, error) {
+	if err := checkTextureSize(width, height); err != nil {
+		return nil, errors.Wrap(err, "failed to create texture")
+	}
+	if pixels != nil && len(pixels) != width*height*format.BytesPerPixel() {
+		return nil, errors.Errorf("failed to create texture: got %d bytes of pixels, %dx%d needs %d",
+			len(pixels), width, height, width*height*format.BytesPerPixel())
+	}
+	return NewTextureFormat(width, height, smooth, format, pixels), nil
+}
+
+// MaxTextureSize returns the maximum width and height of a texture supported by the current OpenGL
+// context (GL_MAX_TEXTURE_SIZE).
+//
+// Init must be called before this function.
+func MaxTextureSize() int {
+	var size int32
+	gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &size)
+	return int(size)
+}
+
+// checkTextureSize returns an error if a texture of the size can't be created.
+func checkTextureSize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errors.Errorf("invalid size %dx%d", width, height)
+	}
+	if max := MaxTextureSize(); width > max || height > max {
+		return errors.Errorf("size %dx%d exceeds the maximum texture size %d", width, height, max)
+	}
+	return nil
+}
+
 func (t *Texture) delete() {
-	mainthread.CallNonBlock(func() {
-		gl.DeleteTextures(1, &t.tex.obj)
-	})
+	mainthread.CallNonBlock(t.release)
+}
+
+// Delete deletes the Texture immediately instead of waiting for the garbage collector, which
+// frees its video memory right away. The Texture must not be used afterwards. Deleting a Texture
+// multiple times is harmless. Deleting the shared placeholder Textures, such as CheckerTexture,
+// does nothing.
+func (t *Texture) Delete() {
+	if t.placeholder {
+		return
+	}
+	runtime.SetFinalizer(t, nil)
+	t.release()
+}
+
+func (t *Texture) release() {
+	gl.DeleteTextures(1, &t.tex.obj)
+	t.tex.invalidate()
 }
 
 // ID returns the OpenGL ID of this Texture.
@@ -80,11 +164,59 @@ func (t *Texture) Height() int {
 	return t.height
 }
 
-// SetPixels sets the content of a sub-region of the Texture. Pixels must be an RGBA byte sequence.
+// Format returns the pixel format of the Texture.
+func (t *Texture) Format() TextureFormat {
+	return t.format
+}
+
+// SetPixels sets the content of a sub-region of the Texture. Pixels must be a sequence of tightly
+// packed rows in the format of the Texture (RGBA bytes by default), no padding is necessary
+// regardless of the width.
 func (t *Texture) SetPixels(x, y, w, h int, pixels []uint8) {
-	if len(pixels) != w*h*4 {
+	if t.compressed {
+		panic("set pixels: compressed texture")
+	}
+	if len(pixels) != w*h*t.format.BytesPerPixel() {
 		panic("set pixels: wrong number of pixels")
 	}
+	t.setPixels(x, y, w, h, 0, pixels)
+}
+
+// SetPixelsRowLength is like SetPixels, except that the rows of the pixels are rowLength pixels
+// long, of which only the first w are used. This way, a sub-region of a larger image can be
+// uploaded without copying it out first.
+func (t *Texture) SetPixelsRowLength(x, y, w, h, rowLength int, pixels []uint8) {
+	if t.compressed {
+		panic("set pixels: compressed texture")
+	}
+	if rowLength < w {
+		panic("set pixels: row length smaller than width")
+	}
+	if h > 0 && len(pixels) < ((h-1)*rowLength+w)*t.format.BytesPerPixel() {
+		panic("set pixels: not enough pixels")
+	}
+	t.setPixels(x, y, w, h, rowLength, pixels)
+}
+
+func (t *Texture) setPixels(x, y, w, h, rowLength int, pixels []uint8) {
+	if w == 0 || h == 0 {
+		// avoid setting 0 bytes of texture data
+		return
+	}
+	if convention.Origin == TopLeft {
+		// copy the rows out, so that they can be flipped without touching the caller's pixels
+		bpp := t.format.BytesPerPixel()
+		if rowLength == 0 {
+			rowLength = w
+		}
+		flipped := make([]uint8, w*h*bpp)
+		for i := 0; i < h; i++ {
+			copy(flipped[i*w*bpp:(i+1)*w*bpp], pixels[i*rowLength*bpp:])
+		}
+		flipRows(flipped, w*bpp)
+		pixels, rowLength, y = flipped, 0, flipY(y, h, t.height)
+	}
+	defer pixelStore(false, 1, rowLength)()
 	gl.TexSubImage2D(
 		gl.TEXTURE_2D,
 		0,
@@ -92,46 +224,283 @@ func (t *Texture) SetPixels(x, y, w, h int, pixels []uint8) {
 		int32(y),
 		int32(w),
 		int32(h),
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
+		t.format.pixelFormat(),
+		t.format.pixelType(),
 		gl.Ptr(pixels),
 	)
 }
 
-// Pixels returns the content of a sub-region of the Texture as an RGBA byte sequence.
+// Pixels returns the content of a sub-region of the Texture as a sequence of tightly packed rows
+// in the format of the Texture (RGBA bytes by default).
 func (t *Texture) Pixels(x, y, w, h int) []uint8 {
-	pixels := make([]uint8, t.width*t.height*4)
+	bpp := t.format.BytesPerPixel()
+	pixels := make([]uint8, t.width*t.height*bpp)
+	if len(pixels) == 0 {
+		return make([]uint8, w*h*bpp)
+	}
+	restore := pixelStore(true, 1, 0)
 	gl.GetTexImage(
 		gl.TEXTURE_2D,
 		0,
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
+		t.format.pixelFormat(),
+		t.format.pixelType(),
 		gl.Ptr(pixels),
 	)
-	subPixels := make([]uint8, w*h*4)
+	restore()
+	y = flipY(y, h, t.height)
+	subPixels := make([]uint8, w*h*bpp)
 	for i := 0; i < h; i++ {
-		row := pixels[(i+y)*t.width*4+x*4 : (i+y)*t.width*4+(x+w)*4]
-		subRow := subPixels[i*w*4 : (i+1)*w*4]
+		row := pixels[(i+y)*t.width*bpp+x*bpp : (i+y)*t.width*bpp+(x+w)*bpp]
+		subRow := subPixels[i*w*bpp : (i+1)*w*bpp]
 		copy(subRow, row)
 	}
+	flipRows(subPixels, w*bpp)
 	return subPixels
 }
 
+// Resize reallocates the storage of the Texture with the new width and height. The Texture keeps
+// its OpenGL ID, so everything referencing it stays valid. A Frame drawing on the Texture doesn't
+// pick up the new size though, resize the Frame by Frame.Resize instead, which resizes its
+// Textures as well.
+//
+// If preserve is true, the old content is copied over on the GPU, anchored at the origin (see
+// SetConvention) and cropped to the new size. Otherwise, the content is undefined. The mipmaps are
+// dropped, call GenerateMipmaps again if needed.
+//
+// Textures with immutable storage can't be resized, this method panics on them.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) Resize(width, height int, preserve bool) {
+	if t.compressed {
+		panic("resize: compressed texture")
+	}
+	if t.immutable() {
+		panic("resize: immutable texture")
+	}
+
+	var tmp uint32
+	if preserve {
+		// copy the old content aside, the texture can't be both the source and the destination
+		gl.GenTextures(1, &tmp)
+		gl.BindTexture(gl.TEXTURE_2D, tmp)
+		gl.TexImage2D(
+			gl.TEXTURE_2D,
+			0,
+			t.format.internalFormat(),
+			int32(t.width),
+			int32(t.height),
+			0,
+			t.format.pixelFormat(),
+			t.format.pixelType(),
+			nil,
+		)
+		copyTexture(t.tex.obj, tmp, 0, 0, 0, 0, t.width, t.height)
+		gl.BindTexture(gl.TEXTURE_2D, t.tex.obj)
+	}
+
+	gl.TexImage2D(
+		gl.TEXTURE_2D,
+		0,
+		t.format.internalFormat(),
+		int32(width),
+		int32(height),
+		0,
+		t.format.pixelFormat(),
+		t.format.pixelType(),
+		nil,
+	)
+	// the old mipmaps have the wrong sizes, which would make the Texture incomplete
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, 0)
+	oldWidth, oldHeight := t.width, t.height
+	t.width, t.height = width, height
+	t.mipmapped = false
+	t.SetSmooth(t.smooth)
+
+	if preserve {
+		w, h := oldWidth, oldHeight
+		if w > width {
+			w = width
+		}
+		if h > height {
+			h = height
+		}
+		copyTexture(tmp, t.tex.obj, 0, flipY(0, h, oldHeight), 0, flipY(0, h, height), w, h)
+		gl.DeleteTextures(1, &tmp)
+	}
+}
+
+// immutable returns whether the storage of the Texture is immutable, i.e. allocated by
+// glTexStorage*, so that its levels can't be respecified. The Texture must be bound.
+func (t *Texture) immutable() bool {
+	var immutable int32
+	gl.GetTexParameteriv(gl.TEXTURE_2D, gl.TEXTURE_IMMUTABLE_FORMAT, &immutable)
+	return immutable != gl.FALSE
+}
+
+// copyTexture copies a w x h rectangle at (sx, sy) in the src texture to (dx, dy) in the dst
+// texture, which must be bound to gl.TEXTURE_2D, through a temporary read framebuffer.
+func copyTexture(src, dst uint32, sx, sy, dx, dy, w, h int) {
+	rf := binder{
+		restoreLoc: gl.READ_FRAMEBUFFER_BINDING,
+		bindFunc: func(obj uint32) {
+			gl.BindFramebuffer(gl.READ_FRAMEBUFFER, obj)
+		},
+	}
+	gl.GenFramebuffers(1, &rf.obj)
+	defer gl.DeleteFramebuffers(1, &rf.obj)
+	rf.bind()
+	defer rf.restore()
+
+	gl.FramebufferTexture2D(gl.READ_FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, src, 0)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	gl.BindTexture(gl.TEXTURE_2D, dst)
+	gl.CopyTexSubImage2D(gl.TEXTURE_2D, 0, int32(dx), int32(dy), int32(sx), int32(sy), int32(w), int32(h))
+}
+
 // SetSmooth sets whether the Texture should be drawn "smoothly" or "pixely".
 //
 // It affects how the Texture is drawn when zoomed. Smooth interpolates between the neighbour
 // pixels, while pixely always chooses the nearest pixel.
+//
+// If the Texture has mipmaps, they're used for minification: smooth interpolates between the
+// two nearest mipmap levels, pixely chooses the nearest one.
 func (t *Texture) SetSmooth(smooth bool) {
 	t.smooth = smooth
-	if smooth {
+	switch {
+	case smooth && t.mipmapped:
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	case smooth:
 		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
 		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	} else {
+	case t.mipmapped:
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST_MIPMAP_NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	default:
 		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
 		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 	}
 }
 
+// Filter is a texture filter, which decides how the texels are sampled when a Texture is drawn
+// smaller (minified) or larger (magnified) than its size.
+type Filter int32
+
+// List of all texture filters. The mipmap filters only apply to minification and require the
+// Texture to have mipmaps (see GenerateMipmaps), otherwise the Texture samples as black.
+const (
+	Nearest              = Filter(gl.NEAREST)
+	Linear               = Filter(gl.LINEAR)
+	NearestMipmapNearest = Filter(gl.NEAREST_MIPMAP_NEAREST)
+	LinearMipmapNearest  = Filter(gl.LINEAR_MIPMAP_NEAREST)
+	NearestMipmapLinear  = Filter(gl.NEAREST_MIPMAP_LINEAR)
+	LinearMipmapLinear   = Filter(gl.LINEAR_MIPMAP_LINEAR)
+)
+
+// SetFilters sets the minification and magnification filters of the Texture directly, overriding
+// the smoothness set by SetSmooth. For example, LinearMipmapLinear minification (trilinear
+// filtering) stops downscaled textures from shimmering. The magnification filter must be Nearest
+// or Linear.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetFilters(min, mag Filter) {
+	if mag != Nearest && mag != Linear {
+		panic("set filters: invalid magnification filter")
+	}
+	t.smooth = mag == Linear
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, int32(min))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, int32(mag))
+}
+
+// GenerateMipmaps generates the full mipmap chain of the Texture from its content using
+// glGenerateMipmap. Call it again after changing the content, the mipmaps don't update
+// automatically.
+//
+// Once generated, the mipmaps are used for minification (see SetSmooth). For more control over
+// the quality of the mipmaps, see GenerateMipmapsCompute. The maximum mipmap level is reset to the
+// default of 1000, which undoes Resize and SetLevelRange.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) GenerateMipmaps() {
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, 1000)
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	t.mipmapped = true
+	t.SetSmooth(t.smooth)
+}
+
+// SetLevelPixels sets the whole content of a mipmap level of the Texture, defining the level if
+// it wasn't defined yet. The size of the level is the size of the Texture halved level times
+// (rounded down, at least 1). Pixels must be a sequence of tightly packed rows in the format of
+// the Texture.
+//
+// Together with SetLevelRange, this allows filling the mipmap levels progressively, as they're
+// streamed in. Once a level other than 0 is set, the Texture counts as mipmapped (see SetSmooth).
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetLevelPixels(level int, pixels []uint8) {
+	if t.compressed {
+		panic("set level pixels: compressed texture")
+	}
+	w, h := t.width, t.height
+	for i := 0; i < level; i++ {
+		w, h = mipSize(w), mipSize(h)
+	}
+	if len(pixels) != w*h*t.format.BytesPerPixel() {
+		panic("set level pixels: wrong number of pixels")
+	}
+	if convention.Origin == TopLeft {
+		pixels = append([]uint8(nil), pixels...)
+		flipRows(pixels, w*t.format.BytesPerPixel())
+	}
+	defer pixelStore(false, 1, 0)()
+	gl.TexImage2D(
+		gl.TEXTURE_2D,
+		int32(level),
+		t.format.internalFormat(),
+		int32(w),
+		int32(h),
+		0,
+		t.format.pixelFormat(),
+		t.format.pixelType(),
+		pixelsPtr(pixels),
+	)
+	if level > 0 {
+		t.mipmapped = true
+	}
+}
+
+// SetLODRange clamps the mipmap level of detail the Texture is sampled at to the range from min to
+// max (GL_TEXTURE_MIN_LOD and GL_TEXTURE_MAX_LOD). The defaults are -1000 and 1000, i.e. no
+// clamping. For example, setting min to 2 makes sampling use at most the third mipmap level, while
+// the larger levels are still being streamed in.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetLODRange(min, max float32) {
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_LOD, min)
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_LOD, max)
+}
+
+// SetLODBias sets the bias added to the mipmap level of detail the Texture is sampled at
+// (GL_TEXTURE_LOD_BIAS). Positive values make the Texture blurrier, negative ones sharper. The
+// default is 0.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetLODBias(bias float32) {
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_LOD_BIAS, bias)
+}
+
+// SetLevelRange sets the range of the mipmap levels of the Texture that are used at all
+// (GL_TEXTURE_BASE_LEVEL and GL_TEXTURE_MAX_LEVEL). Unlike SetLODRange, the levels outside the
+// range don't even have to be defined for the Texture to be complete, so a streaming system can
+// upload the small levels first, set the base level to the largest one uploaded so far and lower
+// it as the larger ones arrive.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) SetLevelRange(base, max int) {
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_BASE_LEVEL, int32(base))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(max))
+}
+
 // Smooth returns whether the Texture is set to be drawn "smooth" or "pixely".
 func (t *Texture) Smooth() bool {
 	return t.smooth
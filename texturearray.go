@@ -0,0 +1,223 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// TextureArray is an OpenGL 2D texture array: a stack of equally sized layers, which a shader
+// samples from a sampler2DArray uniform using a vec3 texture coordinate, the third component
+// being the layer index. For example:
+//
+//   uniform sampler2DArray tiles;
+//   ...
+//   color = texture(tiles, vec3(uv, layer));
+//
+// Unlike the sub-images of an atlas, the layers never bleed into each other when filtered or
+// mipmapped. Like with a Texture, the sampler2DArray uniform is an Int uniform set to the texture
+// unit the TextureArray is bound to.
+type TextureArray struct {
+	tex                   binder
+	width, height, layers int
+	format                TextureFormat
+	compressed            bool // loaded from compressed data, see LoadKTXArray
+	smooth                bool
+	mipmapped             bool
+}
+
+// NewTextureArray creates a new texture array with the specified width and height of each layer,
+// the number of layers and the pixel format. The content of the layers is undefined, use
+// SetLayerPixels to fill them.
+func NewTextureArray(width, height, layers int, smooth bool, format TextureFormat) *TextureArray {
+	ta := &TextureArray{
+		tex: binder{
+			restoreLoc: gl.TEXTURE_BINDING_2D_ARRAY,
+			bindFunc: func(obj uint32) {
+				gl.BindTexture(gl.TEXTURE_2D_ARRAY, obj)
+			},
+		},
+		width:  width,
+		height: height,
+		layers: layers,
+		format: format,
+	}
+
+	gl.GenTextures(1, &ta.tex.obj)
+
+	ta.Begin()
+	defer ta.End()
+
+	gl.TexImage3D(
+		gl.TEXTURE_2D_ARRAY,
+		0,
+		format.internalFormat(),
+		int32(width),
+		int32(height),
+		int32(layers),
+		0,
+		format.pixelFormat(),
+		format.pixelType(),
+		nil,
+	)
+
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	ta.SetSmooth(smooth)
+
+	runtime.SetFinalizer(ta, (*TextureArray).delete)
+
+	return ta
+}
+
+// MaxArrayTextureLayers returns the maximum number of layers of a TextureArray supported by the
+// current OpenGL context (GL_MAX_ARRAY_TEXTURE_LAYERS).
+//
+// Init must be called before this function.
+func MaxArrayTextureLayers() int {
+	var layers int32
+	gl.GetIntegerv(gl.MAX_ARRAY_TEXTURE_LAYERS, &layers)
+	return int(layers)
+}
+
+func (ta *TextureArray) delete() {
+	mainthread.CallNonBlock(ta.release)
+}
+
+// Delete deletes the TextureArray immediately instead of waiting for the garbage collector. The
+// TextureArray must not be used afterwards. Deleting a TextureArray multiple times is harmless.
+func (ta *TextureArray) Delete() {
+	runtime.SetFinalizer(ta, nil)
+	ta.release()
+}
+
+func (ta *TextureArray) release() {
+	gl.DeleteTextures(1, &ta.tex.obj)
+	ta.tex.invalidate()
+}
+
+// ID returns the OpenGL ID of this TextureArray.
+func (ta *TextureArray) ID() uint32 {
+	return ta.tex.obj
+}
+
+// Width returns the width of each layer of the TextureArray in pixels.
+func (ta *TextureArray) Width() int {
+	return ta.width
+}
+
+// Height returns the height of each layer of the TextureArray in pixels.
+func (ta *TextureArray) Height() int {
+	return ta.height
+}
+
+// Layers returns the number of layers of the TextureArray.
+func (ta *TextureArray) Layers() int {
+	return ta.layers
+}
+
+// Format returns the pixel format of the TextureArray.
+func (ta *TextureArray) Format() TextureFormat {
+	return ta.format
+}
+
+// SetLayerPixels sets the content of a sub-region of a layer of the TextureArray. Pixels must be
+// a sequence of tightly packed rows in the format of the TextureArray, no padding is necessary
+// regardless of the width.
+//
+// The TextureArray must be bound before calling this method.
+func (ta *TextureArray) SetLayerPixels(layer, x, y, w, h int, pixels []uint8) {
+	if ta.compressed {
+		panic("set layer pixels: compressed texture array")
+	}
+	if layer < 0 || layer >= ta.layers {
+		panic("set layer pixels: layer out of range")
+	}
+	if len(pixels) != w*h*ta.format.BytesPerPixel() {
+		panic("set layer pixels: wrong number of pixels")
+	}
+	if w == 0 || h == 0 {
+		// avoid setting 0 bytes of texture data
+		return
+	}
+	if convention.Origin == TopLeft {
+		pixels = append([]uint8(nil), pixels...)
+		flipRows(pixels, w*ta.format.BytesPerPixel())
+		y = flipY(y, h, ta.height)
+	}
+	defer pixelStore(false, 1, 0)()
+	gl.TexSubImage3D(
+		gl.TEXTURE_2D_ARRAY,
+		0,
+		int32(x),
+		int32(y),
+		int32(layer),
+		int32(w),
+		int32(h),
+		1,
+		ta.format.pixelFormat(),
+		ta.format.pixelType(),
+		gl.Ptr(pixels),
+	)
+}
+
+// LayerPixels returns the whole content of a layer of the TextureArray as a sequence of tightly
+// packed rows in the format of the TextureArray.
+func (ta *TextureArray) LayerPixels(layer int) []uint8 {
+	if ta.compressed {
+		panic("layer pixels: compressed texture array")
+	}
+	if layer < 0 || layer >= ta.layers {
+		panic("layer pixels: layer out of range")
+	}
+	return ReadLayer(gl.TEXTURE_2D_ARRAY, ta.tex.obj, 0, layer, ta.width, ta.height, ta.format)
+}
+
+// SetSmooth sets whether the TextureArray should be drawn "smoothly" or "pixely", see
+// Texture.SetSmooth.
+//
+// The TextureArray must be bound before calling this method.
+func (ta *TextureArray) SetSmooth(smooth bool) {
+	ta.smooth = smooth
+	min, mag := Nearest, Nearest
+	switch {
+	case smooth && ta.mipmapped:
+		min, mag = LinearMipmapLinear, Linear
+	case smooth:
+		min, mag = Linear, Linear
+	case ta.mipmapped:
+		min = NearestMipmapNearest
+	}
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MIN_FILTER, int32(min))
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAG_FILTER, int32(mag))
+}
+
+// Smooth returns whether the TextureArray is set to be drawn "smooth" or "pixely".
+func (ta *TextureArray) Smooth() bool {
+	return ta.smooth
+}
+
+// GenerateMipmaps generates the full mipmap chain of each layer of the TextureArray. Call it again
+// after changing the content, the mipmaps don't update automatically.
+//
+// The TextureArray must be bound before calling this method.
+func (ta *TextureArray) GenerateMipmaps() {
+	if ta.compressed {
+		panic("generate mipmaps: compressed texture array")
+	}
+	gl.GenerateMipmap(gl.TEXTURE_2D_ARRAY)
+	ta.mipmapped = true
+	ta.SetSmooth(ta.smooth)
+}
+
+// Begin binds the TextureArray. This is necessary before using the TextureArray.
+func (ta *TextureArray) Begin() {
+	ta.tex.bind()
+}
+
+// End unbinds the TextureArray and restores the previous one.
+func (ta *TextureArray) End() {
+	ta.tex.restore()
+}
@@ -0,0 +1,137 @@
+package glhf
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/pkg/errors"
+)
+
+// LocalSize is the size of a compute work group in X, Y and Z.
+type LocalSize [3]int
+
+// DefaultLocalSizes is a reasonable set of candidate work group sizes for 2D kernels.
+var DefaultLocalSizes = []LocalSize{
+	{8, 8, 1}, {16, 16, 1}, {32, 8, 1}, {8, 32, 1}, {32, 32, 1}, {64, 1, 1}, {256, 1, 1},
+}
+
+// tunedSizes maps a GPU fingerprint and a shader source to the fastest work group size.
+var tunedSizes = make(map[string]LocalSize)
+
+// TuneComputeProgram creates a Compute program with the fastest work group size for the current
+// GPU out of the candidates (DefaultLocalSizes if nil). The ideal size differs widely between
+// vendors, so instead of hardcoding it, let the tuner pick.
+//
+// The computeShader source must declare its work group size using the macros LOCAL_SIZE_X,
+// LOCAL_SIZE_Y and LOCAL_SIZE_Z, which are defined by the tuner:
+//   layout(local_size_x = LOCAL_SIZE_X, local_size_y = LOCAL_SIZE_Y, local_size_z = LOCAL_SIZE_Z) in;
+//
+// On the first call for a source, the program is compiled with each candidate size and the run
+// function is called with it a few times, with the program bound. The run function should set up
+// typical inputs and dispatch the work, adapting the number of work groups to the size. The
+// dispatches are timed on the GPU and the fastest size wins. The result is cached per GPU (see
+// TunedSizes), so later calls only compile the winner.
+//
+// Candidates that exceed the limits of the GPU or fail to compile are skipped. If none of them
+// works, an error is returned.
+func TuneComputeProgram(uniformFmt AttrFormat, computeShader string, candidates []LocalSize, run func(c *Compute, size LocalSize)) (*Compute, LocalSize, error) {
+	if candidates == nil {
+		candidates = DefaultLocalSizes
+	}
+
+	key := tuneKey(computeShader)
+	if size, ok := tunedSizes[key]; ok {
+		c, err := newComputeLocalSize(uniformFmt, computeShader, size)
+		return c, size, err
+	}
+
+	const iterations = 5
+
+	var (
+		best     *Compute
+		bestSize LocalSize
+		bestTime time.Duration
+		lastErr  error
+	)
+	limits := ComputeLimits()
+	for _, size := range candidates {
+		if size[0]*size[1]*size[2] > limits.Invocations ||
+			size[0] > limits.Size[0] || size[1] > limits.Size[1] || size[2] > limits.Size[2] {
+			continue
+		}
+		c, err := newComputeLocalSize(uniformFmt, computeShader, size)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.Begin()
+		run(c, size) // warm up, the first run may include deferred compilation
+		c.SetProfiling(true)
+		for i := 0; i < iterations; i++ {
+			run(c, size)
+		}
+		c.SetProfiling(false)
+		c.End()
+		gl.Finish()
+
+		var total time.Duration
+		for _, t := range c.Timings() {
+			total += t
+		}
+		if best != nil && total >= bestTime {
+			c.Delete()
+			continue
+		}
+		if best != nil {
+			best.Delete()
+		}
+		best, bestSize, bestTime = c, size, total
+	}
+
+	if best == nil {
+		if lastErr == nil {
+			lastErr = errors.New("no candidate work group size fits the GPU")
+		}
+		return nil, LocalSize{}, errors.Wrap(lastErr, "failed to tune compute program")
+	}
+	tunedSizes[key] = bestSize
+	return best, bestSize, nil
+}
+
+// TunedSizes returns the work group sizes picked by TuneComputeProgram so far, keyed by the GPU
+// fingerprint and the shader source. Save them and restore them with SetTunedSizes to skip the
+// tuning on the next run.
+func TunedSizes() map[string]LocalSize {
+	sizes := make(map[string]LocalSize, len(tunedSizes))
+	for key, size := range tunedSizes {
+		sizes[key] = size
+	}
+	return sizes
+}
+
+// SetTunedSizes adds previously saved work group sizes (see TunedSizes). Sizes for other GPUs
+// are kept, but never used.
+func SetTunedSizes(sizes map[string]LocalSize) {
+	for key, size := range sizes {
+		tunedSizes[key] = size
+	}
+}
+
+// tuneKey returns the cache key for the shader source on the current GPU.
+func tuneKey(source string) string {
+	h := fnv.New64a()
+	fmt.Fprint(h, driver.Vendor, "\x00", driver.Renderer, "\x00", driver.Version, "\x00", source)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func newComputeLocalSize(uniformFmt AttrFormat, computeShader string, size LocalSize) (*Compute, error) {
+	return NewComputeProgram(uniformFmt, insertDefines(computeShader, map[string]string{
+		"LOCAL_SIZE_X": strconv.Itoa(size[0]),
+		"LOCAL_SIZE_Y": strconv.Itoa(size[1]),
+		"LOCAL_SIZE_Z": strconv.Itoa(size[2]),
+	}))
+}
@@ -0,0 +1,115 @@
+package glhf
+
+import (
+	"runtime"
+
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// Upload is an asynchronous upload of pixels to a Texture started by UploadAsync.
+type Upload struct {
+	pbo   binder
+	fence uintptr
+}
+
+// UploadAsync is like SetPixels, except that it doesn't block until the pixels are transferred
+// to the GPU, which can take several milliseconds for multi-megabyte textures.
+//
+// The pixels are copied into a pixel unpack buffer and the texture is updated from it in the
+// background. Until Done returns true, the content of the sub-region of the Texture is
+// undefined. The pixels can be reused right after this method returns.
+//
+// The Texture must be bound before calling this method.
+func (t *Texture) UploadAsync(x, y, w, h int, pixels []uint8) *Upload {
+	if t.compressed {
+		panic("upload async: compressed texture")
+	}
+	if len(pixels) != w*h*t.format.BytesPerPixel() {
+		panic("upload async: wrong number of pixels")
+	}
+	if convention.Origin == TopLeft {
+		pixels = append([]uint8(nil), pixels...)
+		flipRows(pixels, w*t.format.BytesPerPixel())
+		y = flipY(y, h, t.height)
+	}
+
+	u := &Upload{
+		pbo: binder{
+			restoreLoc: gl.PIXEL_UNPACK_BUFFER_BINDING,
+			bindFunc: func(obj uint32) {
+				gl.BindBuffer(gl.PIXEL_UNPACK_BUFFER, obj)
+			},
+		},
+	}
+
+	gl.GenBuffers(1, &u.pbo.obj)
+
+	u.pbo.bind()
+	if len(pixels) > 0 {
+		gl.BufferData(gl.PIXEL_UNPACK_BUFFER, len(pixels), gl.Ptr(pixels), gl.STREAM_DRAW)
+	}
+	restore := pixelStore(false, 1, 0)
+	gl.TexSubImage2D(
+		gl.TEXTURE_2D,
+		0,
+		int32(x),
+		int32(y),
+		int32(w),
+		int32(h),
+		t.format.pixelFormat(),
+		t.format.pixelType(),
+		gl.PtrOffset(0), // from the bound pixel unpack buffer
+	)
+	restore()
+	u.pbo.restore()
+
+	u.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+
+	runtime.SetFinalizer(u, (*Upload).delete)
+
+	return u
+}
+
+func (u *Upload) delete() {
+	mainthread.CallNonBlock(u.release)
+}
+
+func (u *Upload) release() {
+	if u.fence != 0 {
+		gl.DeleteSync(u.fence)
+		u.fence = 0
+	}
+	gl.DeleteBuffers(1, &u.pbo.obj)
+	u.pbo.invalidate()
+}
+
+// Done returns whether the upload has finished, without waiting for it. Once it returns true, the
+// staging buffer is freed.
+func (u *Upload) Done() bool {
+	if u.fence == 0 {
+		return true
+	}
+	status := gl.ClientWaitSync(u.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 0)
+	if status == gl.TIMEOUT_EXPIRED {
+		return false
+	}
+	runtime.SetFinalizer(u, nil)
+	u.release()
+	return true
+}
+
+// Wait waits until the upload has finished and frees the staging buffer.
+func (u *Upload) Wait() {
+	if u.fence == 0 {
+		return
+	}
+	for {
+		status := gl.ClientWaitSync(u.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+		if status != gl.TIMEOUT_EXPIRED {
+			break
+		}
+	}
+	runtime.SetFinalizer(u, nil)
+	u.release()
+}
@@ -1,17 +1,36 @@
 package glhf
 
-import "github.com/go-gl/gl/v3.3-core/gl"
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+// pixelsPtr returns a pointer to the first of the pixels, or nil if there are none, because
+// gl.Ptr doesn't accept empty slices. Passing nil to glTexImage* leaves the storage undefined.
+func pixelsPtr(pixels []uint8) unsafe.Pointer {
+	if len(pixels) == 0 {
+		return nil
+	}
+	return gl.Ptr(pixels)
+}
 
 type binder struct {
 	restoreLoc uint32
 	bindFunc   func(uint32)
 
 	obj uint32
+	gen uint32 // incremented each time obj is deleted or recreated
 
 	prev []uint32
 }
 
 func (b *binder) bind() *binder {
+	if debug && b.obj == 0 && b.gen != 0 {
+		panic(fmt.Sprintf("glhf: use of a deleted %s (generation %d)", b.kind(), b.gen))
+	}
+
 	var prev int32
 	gl.GetIntegerv(b.restoreLoc, &prev)
 	b.prev = append(b.prev, uint32(prev))
@@ -29,3 +48,25 @@ func (b *binder) restore() *binder {
 	b.prev = b.prev[:len(b.prev)-1]
 	return b
 }
+
+// invalidate forgets the deleted object and bumps the generation, so that any further use of the
+// binder is caught in debug mode.
+func (b *binder) invalidate() {
+	b.obj = 0
+	b.gen++
+}
+
+// kind returns a human-readable name of the kind of objects bound by the binder.
+func (b *binder) kind() string {
+	switch b.restoreLoc {
+	case gl.CURRENT_PROGRAM:
+		return "shader"
+	case gl.TEXTURE_BINDING_2D:
+		return "texture"
+	case gl.FRAMEBUFFER_BINDING:
+		return "frame"
+	case gl.VERTEX_ARRAY_BINDING, gl.ARRAY_BUFFER_BINDING:
+		return "vertex slice"
+	}
+	return "object"
+}
@@ -3,6 +3,7 @@ package glhf
 import (
 	"fmt"
 	"runtime"
+	"unsafe"
 
 	"github.com/faiface/mainthread"
 	"github.com/go-gl/gl/v3.3-core/gl"
@@ -19,8 +20,42 @@ import (
 // Note that you need to Begin a VertexSlice before getting or updating it's elements or drawing it.
 // After you're done with it, you need to End it.
 type VertexSlice struct {
-	va   *vertexArray
-	i, j int
+	va        *vertexArray
+	i, j      int
+	primitive Primitive
+}
+
+// Primitive is the kind of primitives that the vertices of a VertexSlice are assembled into when
+// drawing.
+type Primitive int
+
+// List of all primitives.
+const (
+	Triangles Primitive = iota
+	TriangleStrip
+	TriangleFan
+	Lines
+	LineStrip
+	Points
+)
+
+func (p Primitive) glMode() uint32 {
+	switch p {
+	case Triangles:
+		return gl.TRIANGLES
+	case TriangleStrip:
+		return gl.TRIANGLE_STRIP
+	case TriangleFan:
+		return gl.TRIANGLE_FAN
+	case Lines:
+		return gl.LINES
+	case LineStrip:
+		return gl.LINE_STRIP
+	case Points:
+		return gl.POINTS
+	default:
+		panic("invalid primitive")
+	}
 }
 
 // MakeVertexSlice allocates a new vertex array with specified capacity and returns a VertexSlice
@@ -29,11 +64,98 @@ type VertexSlice struct {
 // Note, that a vertex array is specialized for a specific shader and can't be used with another
 // shader.
 func MakeVertexSlice(shader *Shader, len, cap int) *VertexSlice {
+	return MakeVertexSliceUsage(shader, len, cap, DynamicDraw)
+}
+
+// MakeVertexSliceUsage is like MakeVertexSlice, but lets you specify how the contents of the
+// vertex array will be used, so that the driver can place it in the most suitable memory.
+// MakeVertexSlice uses DynamicDraw.
+func MakeVertexSliceUsage(shader *Shader, len, cap int, usage BufferUsage) *VertexSlice {
 	if len > cap {
 		panic("failed to make vertex slice: len > cap")
 	}
 	return &VertexSlice{
-		va: newVertexArray(shader, cap),
+		va: newVertexArray(shader, shader.VertexFormat(), cap, usage, 0),
+		i:  0,
+		j:  len,
+	}
+}
+
+// MakePersistentVertexSlice is like MakeVertexSlice, but the underlying vertex buffer stays
+// mapped into the client memory for its entire lifetime. MappedVertexData then gives direct
+// access to the GPU-visible memory, which avoids the copies done by SetVertexData.
+//
+// Persistent mapping requires OpenGL 4.4 or the GL_ARB_buffer_storage extension, an error is
+// returned if neither is available.
+func MakePersistentVertexSlice(shader *Shader, len, cap int) (*VertexSlice, error) {
+	if len > cap {
+		panic("failed to make vertex slice: len > cap")
+	}
+	if !bufferStorageSupported() {
+		return nil, errors.New("failed to make persistent vertex slice: OpenGL 4.4 or GL_ARB_buffer_storage required")
+	}
+	va := newVertexArray(shader, shader.VertexFormat(), cap, DynamicDraw, persistentVertexArray)
+	return &VertexSlice{
+		va: va,
+		i:  0,
+		j:  len,
+	}, nil
+}
+
+// MakePlanarVertexSlice is like MakeVertexSliceUsage, but instead of storing the vertices in a
+// single buffer with interleaved attributes, the underlying vertex array has one buffer per
+// attribute.
+//
+// This way, a single attribute can be updated using SetAttrData, without touching the others.
+// For example, positions of an animated mesh can be updated every frame, while texture
+// coordinates stay untouched. All other methods work just like with an interleaved VertexSlice,
+// but SetVertexData and VertexData need to split or merge the attributes on the CPU.
+func MakePlanarVertexSlice(shader *Shader, len, cap int, usage BufferUsage) *VertexSlice {
+	if len > cap {
+		panic("failed to make vertex slice: len > cap")
+	}
+	return &VertexSlice{
+		va: newVertexArray(shader, shader.VertexFormat(), cap, usage, planarVertexArray),
+		i:  0,
+		j:  len,
+	}
+}
+
+// BufferUsage is a hint to the driver about how the contents of a buffer will be used.
+type BufferUsage uint32
+
+// List of all buffer usages.
+const (
+	// StaticDraw is for contents that are set once and drawn many times, e.g. static meshes.
+	StaticDraw = BufferUsage(gl.STATIC_DRAW)
+
+	// DynamicDraw is for contents that are changed from time to time and drawn many times.
+	DynamicDraw = BufferUsage(gl.DYNAMIC_DRAW)
+
+	// StreamDraw is for contents that are rewritten (almost) every time they're drawn, e.g.
+	// sprite batches rebuilt every frame.
+	//
+	// Vertex slices with this usage are orphaned when rewritten as a whole, so the GPU can keep
+	// drawing the old contents without a stall. Use MakePersistentVertexSlice to write into
+	// persistently mapped memory instead.
+	StreamDraw = BufferUsage(gl.STREAM_DRAW)
+)
+
+// MakeInstanceSlice allocates a new vertex array of per-instance attributes with specified
+// capacity and returns a VertexSlice that points to it's first len elements.
+//
+// Unlike MakeVertexSlice, the format of the attributes is not taken from the shader, but
+// supplied explicitly. The attributes must, of course, be declared as inputs in the vertex shader
+// of the supplied shader, they just advance once per instance instead of once per vertex.
+//
+// An instance slice isn't drawn on its own. Instead, it's attached to a regular VertexSlice
+// using SetInstances and then drawn using DrawInstanced.
+func MakeInstanceSlice(shader *Shader, format AttrFormat, len, cap int) *VertexSlice {
+	if len > cap {
+		panic("failed to make instance slice: len > cap")
+	}
+	return &VertexSlice{
+		va: newVertexArray(shader, format, cap, DynamicDraw, 0),
 		i:  0,
 		j:  len,
 	}
@@ -67,37 +189,68 @@ func (vs *VertexSlice) SetLen(len int) {
 	vs.Begin()
 }
 
+// Append appends the vertices in data to the end of the VertexSlice, growing the underlying vertex
+// array if its capacity is exceeded (see SetGrowthFactor). The data is in the same format as with
+// SetVertexData and must contain whole vertices.
+//
+// The VertexSlice must be bound before calling this method.
+func (vs *VertexSlice) Append(data []float32) {
+	stride := vs.Stride()
+	if len(data)%stride != 0 {
+		panic("append: data must contain whole vertices")
+	}
+	n := vs.Len()
+	vs.SetLen(n + len(data)/stride)
+	vs.Slice(n, vs.Len()).SetVertexData(data)
+}
+
+// SetGrowthFactor sets how much the capacity of the underlying vertex array is multiplied when
+// SetLen or Append exceed it. The factor must be greater than 1. By default (or if set to 0), the
+// capacity is doubled while small and grows by 25% once it's over 1024 vertices.
+//
+// The old contents are copied to the new vertex array on the GPU.
+func (vs *VertexSlice) SetGrowthFactor(factor float64) {
+	if factor != 0 && factor <= 1 {
+		panic("set growth factor: factor must be greater than 1")
+	}
+	vs.va.growth = factor
+}
+
 // grow returns supplied vs with length changed to len. Allocates new underlying vertex array if
 // necessary. The original content is preserved.
 func (vs VertexSlice) grow(len int) VertexSlice {
 	if len <= vs.Cap() {
 		// capacity sufficient
 		return VertexSlice{
-			va: vs.va,
-			i:  vs.i,
-			j:  vs.i + len,
+			va:        vs.va,
+			i:         vs.i,
+			j:         vs.i + len,
+			primitive: vs.primitive,
 		}
 	}
 
 	// grow the capacity
 	newCap := vs.Cap()
-	if newCap < 1024 {
+	switch {
+	case vs.va.growth != 0:
+		newCap = int(float64(newCap) * vs.va.growth)
+	case newCap < 1024:
 		newCap += newCap
-	} else {
+	default:
 		newCap += newCap / 4
 	}
 	if newCap < len {
 		newCap = len
 	}
 	newVs := VertexSlice{
-		va: newVertexArray(vs.va.shader, newCap),
-		i:  0,
-		j:  len,
+		va:        newVertexArray(vs.va.shader, vs.va.format, newCap, vs.va.usage, vs.va.flags),
+		i:         0,
+		j:         len,
+		primitive: vs.primitive,
 	}
+	newVs.va.growth = vs.va.growth
 	// preserve the original content
-	newVs.Begin()
-	newVs.Slice(0, vs.Len()).SetVertexData(vs.VertexData())
-	newVs.End()
+	vs.CopyTo(&newVs, 0, 0, vs.Len())
 	return newVs
 }
 
@@ -107,13 +260,24 @@ func (vs VertexSlice) grow(len int) VertexSlice {
 // Note, that the returned VertexSlice shares an underlying vertex array with the original
 // VertexSlice. Modifying the contents of one modifies corresponding contents of the other.
 func (vs *VertexSlice) Slice(i, j int) *VertexSlice {
+	dst := &VertexSlice{}
+	vs.SubsliceInto(dst, i, j)
+	return dst
+}
+
+// SubsliceInto is like Slice, but instead of allocating a new VertexSlice, it overwrites dst with
+// the sub-slice. Reusing the same dst every frame makes slicing big batches garbage-free.
+//
+// It's fine for dst to be the same as this VertexSlice.
+func (vs *VertexSlice) SubsliceInto(dst *VertexSlice, i, j int) {
 	if i < 0 || j < i || j > vs.va.cap {
 		panic("failed to slice vertex slice: index out of range")
 	}
-	return &VertexSlice{
-		va: vs.va,
-		i:  vs.i + i,
-		j:  vs.i + j,
+	*dst = VertexSlice{
+		va:        vs.va,
+		i:         vs.i + i,
+		j:         vs.i + j,
+		primitive: vs.primitive,
 	}
 }
 
@@ -124,6 +288,14 @@ func (vs *VertexSlice) Slice(i, j int) *VertexSlice {
 // attribues in the data slice must be in the same order as in the vertex format of this Vertex
 // Slice.
 //
+// Integer attributes (Int, IVec2, ..., Uint, UVec2, ...) occupy one element per component as
+// well, but the elements hold the bits of the integers, not their values. Use
+// math.Float32frombits to store them, e.g. math.Float32frombits(uint32(boneIndex)).
+//
+// Compact attributes (UByte4Norm, Half2, ...) are packed into elements using the Pack functions,
+// e.g. PackUByte4Norm for a color. Types with 4 bytes per attribute occupy 1 element, types with 8
+// bytes occupy 2 elements.
+//
 // If the length of vertices does not match the length of the VertexSlice, this methdo panics.
 func (vs *VertexSlice) SetVertexData(data []float32) {
 	if len(data)/vs.Stride() != vs.Len() {
@@ -133,6 +305,106 @@ func (vs *VertexSlice) SetVertexData(data []float32) {
 	vs.va.setVertexData(vs.i, vs.j, data)
 }
 
+// SetVertexDataAt sets the contents of a part of the VertexSlice, starting with the vertex at
+// the index firstVertex (relative to this VertexSlice). The data is in the same format as with
+// SetVertexData and must contain whole vertices. Only the touched range is uploaded, so updating a
+// single sprite in a large batch is cheap.
+//
+// If the data doesn't fit into the VertexSlice, this method panics.
+func (vs *VertexSlice) SetVertexDataAt(firstVertex int, data []float32) {
+	stride := vs.Stride()
+	if len(data)%stride != 0 {
+		panic("set vertex data at: data must contain whole vertices")
+	}
+	n := len(data) / stride
+	if firstVertex < 0 || firstVertex+n > vs.Len() {
+		panic("set vertex data at: index out of range")
+	}
+	vs.va.setVertexData(vs.i+firstVertex, vs.i+firstVertex+n, data)
+}
+
+// StreamVertexData sets the contents of the VertexSlice just like SetVertexData, but without
+// waiting for the GPU to finish drawing the previous contents.
+//
+// Use it for slices that are rewritten every frame. With SetVertexData, the driver has to wait
+// until the previous frame finishes reading the buffer before overwriting it. StreamVertexData
+// instead tells the driver that the previous contents are no longer needed, so it's free to give
+// the new data a fresh piece of memory.
+//
+// If the VertexSlice covers the whole underlying vertex array, the buffer is orphaned (reallocated
+// with glBufferData), otherwise the range of the VertexSlice is mapped with
+// GL_MAP_INVALIDATE_RANGE_BIT. Either way, the contents of the VertexSlice must be set as a whole,
+// there's no way to update just a part of it.
+func (vs *VertexSlice) StreamVertexData(data []float32) {
+	if len(data)/vs.Stride() != vs.Len() {
+		panic("stream vertex data: wrong length of vertices")
+	}
+	vs.va.streamVertexData(vs.i, vs.j, data)
+}
+
+// MappedVertexData returns the contents of a VertexSlice created by MakePersistentVertexSlice
+// directly as the GPU-visible memory of the underlying vertex buffer. The layout of the data is
+// the same as with SetVertexData. Writes to the returned slice are visible to the GPU without any
+// further calls, but the slice is write-only, reading from it is undefined.
+//
+// Before returning, this method waits until the GPU finishes all draws of the underlying vertex
+// array issued so far, so the data can be safely overwritten. Don't keep the returned slice
+// around across draws, call this method again instead.
+//
+// If the VertexSlice isn't persistently mapped, this method panics. The returned slice is
+// invalidated when the VertexSlice is reallocated (e.g. by SetLen).
+func (vs *VertexSlice) MappedVertexData() []float32 {
+	if vs.va.mapped == nil {
+		panic("mapped vertex data: vertex slice is not persistently mapped")
+	}
+	vs.va.waitFence()
+	stride := vs.Stride()
+	return vs.va.mapped[vs.i*stride : vs.j*stride : vs.j*stride]
+}
+
+// MapVertexData maps the contents of the VertexSlice into the client memory and calls fn with
+// it, so it can be written in place without the intermediate copy done by SetVertexData. The
+// layout of the data is the same as with SetVertexData. The mapping is released when fn returns,
+// so don't keep the slice around.
+//
+// The data is write-only, reading from it is undefined. Elements not written by fn keep their
+// previous values.
+func (vs *VertexSlice) MapVertexData(fn func(data []float32)) {
+	if vs.va.mapped != nil {
+		fn(vs.MappedVertexData())
+		return
+	}
+	vs.va.mapVertexData(vs.i, vs.j, fn)
+}
+
+// SetAttrData sets the values of a single attribute of all vertices of a VertexSlice created by
+// MakePlanarVertexSlice. The attribute is specified by the index in the vertex format. The data
+// contains the values of the attribute one vertex after another, in the same format as in
+// SetVertexData.
+//
+// If the VertexSlice isn't planar or the length of the data doesn't match the length of the
+// VertexSlice, this method panics.
+func (vs *VertexSlice) SetAttrData(attr int, data []float32) {
+	if vs.va.flags&planarVertexArray == 0 {
+		panic("set attr data: vertex slice is not planar")
+	}
+	if len(data) != vs.Len()*vs.va.format[attr].Type.Size()/4 {
+		panic("set attr data: wrong length of vertices")
+	}
+	vs.va.setAttrData(attr, vs.i, data)
+}
+
+// AttrData returns the values of a single attribute of all vertices of a VertexSlice created by
+// MakePlanarVertexSlice.
+//
+// The data is in the same format as with SetAttrData.
+func (vs *VertexSlice) AttrData(attr int) []float32 {
+	if vs.va.flags&planarVertexArray == 0 {
+		panic("attr data: vertex slice is not planar")
+	}
+	return vs.va.attrData(attr, vs.i, vs.j)
+}
+
 // VertexData returns the contents of the VertexSlice.
 //
 // The data is in the same format as with SetVertexData.
@@ -140,9 +412,160 @@ func (vs *VertexSlice) VertexData() []float32 {
 	return vs.va.vertexData(vs.i, vs.j)
 }
 
+// SetPrimitive sets the kind of primitives the VertexSlice is drawn as. The default is Triangles.
+//
+// Sub-slices created by Slice inherit the primitive of the original VertexSlice.
+func (vs *VertexSlice) SetPrimitive(primitive Primitive) {
+	vs.primitive = primitive
+}
+
+// Primitive returns the kind of primitives the VertexSlice is drawn as.
+func (vs *VertexSlice) Primitive() Primitive {
+	return vs.primitive
+}
+
 // Draw draws the content of the VertexSlice.
 func (vs *VertexSlice) Draw() {
-	vs.va.draw(vs.i, vs.j)
+	vs.va.draw(vs.primitive, vs.i, vs.j)
+}
+
+// DrawPrimitive draws the content of the VertexSlice as the specified kind of primitives,
+// regardless of the primitive set by SetPrimitive.
+func (vs *VertexSlice) DrawPrimitive(primitive Primitive) {
+	vs.va.draw(primitive, vs.i, vs.j)
+}
+
+// SetInstances attaches an instance slice (see MakeInstanceSlice) to this VertexSlice. The first
+// instance drawn by DrawInstanced takes its per-instance attributes from the first element of
+// the instance slice, the second one from the second element, and so on.
+//
+// The attachment refers to the vertex array the instance slice points to at the time of this
+// call. If the instance slice gets reallocated (e.g. by SetLen exceeding its capacity), call
+// SetInstances again.
+func (vs *VertexSlice) SetInstances(instances *VertexSlice) {
+	vs.va.vao.bind()
+	instances.va.vbo.bind()
+	instances.va.attribPointers(instances.va.shader, instances.i*instances.va.stride, 1)
+	instances.va.vbo.restore()
+	vs.va.vao.restore()
+}
+
+// DrawInstanced draws the content of the VertexSlice count times in a single draw call. Per-instance
+// attributes are taken from the instance slice attached with SetInstances.
+//
+// The shader can also tell the instances apart using gl_InstanceID.
+func (vs *VertexSlice) DrawInstanced(count int) {
+	vs.va.drawInstanced(vs.primitive, vs.i, vs.j, count)
+}
+
+// CopyTo copies count vertices starting at srcOffset in this VertexSlice to dst, starting at
+// dstOffset in dst. The offsets are relative to the VertexSlices. The copy happens entirely on the
+// GPU, nothing is read back to the CPU, so it's suitable for compacting or defragmenting large
+// buffers.
+//
+// Both VertexSlices must have the same vertex format and layout (interleaved or planar). They
+// may share the underlying vertex array, but the copied ranges must not overlap. Neither of them
+// needs to be bound.
+func (vs *VertexSlice) CopyTo(dst *VertexSlice, srcOffset, dstOffset, count int) {
+	if srcOffset < 0 || dstOffset < 0 || count < 0 || srcOffset+count > vs.Len() || dstOffset+count > dst.Len() {
+		panic("copy to: index out of range")
+	}
+	if vs.va.stride != dst.va.stride || len(vs.va.format) != len(dst.va.format) ||
+		vs.va.flags&planarVertexArray != dst.va.flags&planarVertexArray {
+		panic("copy to: incompatible vertex slices")
+	}
+	if count == 0 {
+		return
+	}
+	srcVbos, dstVbos := []uint32{vs.va.vbo.obj}, []uint32{dst.va.vbo.obj}
+	sizes := []int{vs.va.stride}
+	if vs.va.attrVbos != nil {
+		srcVbos, dstVbos = vs.va.attrVbos, dst.va.attrVbos
+		sizes = sizes[:0]
+		for _, attr := range vs.va.format {
+			sizes = append(sizes, attr.Type.Size())
+		}
+	}
+	// the binding of the copy targets is queried by the targets themselves
+	read := binder{
+		restoreLoc: gl.COPY_READ_BUFFER,
+		bindFunc: func(obj uint32) {
+			gl.BindBuffer(gl.COPY_READ_BUFFER, obj)
+		},
+	}
+	write := binder{
+		restoreLoc: gl.COPY_WRITE_BUFFER,
+		bindFunc: func(obj uint32) {
+			gl.BindBuffer(gl.COPY_WRITE_BUFFER, obj)
+		},
+	}
+	for k := range srcVbos {
+		read.obj, write.obj = srcVbos[k], dstVbos[k]
+		read.bind()
+		write.bind()
+		gl.CopyBufferSubData(
+			gl.COPY_READ_BUFFER,
+			gl.COPY_WRITE_BUFFER,
+			(vs.i+srcOffset)*sizes[k],
+			(dst.i+dstOffset)*sizes[k],
+			count*sizes[k],
+		)
+		write.restore()
+		read.restore()
+	}
+	dst.va.placeFence()
+}
+
+// MultiDrawIndirect draws the VertexSlice according to count commands of the DrawIndirectBuffer,
+// starting with the i-th one, all in a single draw call. The commands are read directly by the
+// GPU, so they may have been written by a compute shader.
+//
+// Note, that the first vertex of each command is an index into the whole underlying vertex array,
+// not into the VertexSlice. Per-instance attributes are taken from the instance slice attached
+// with SetInstances, starting with the first instance of each command.
+//
+// This method requires MultiDrawIndirectSupported, otherwise it panics.
+func (vs *VertexSlice) MultiDrawIndirect(buf *DrawIndirectBuffer, i, count int) {
+	if !MultiDrawIndirectSupported() {
+		panic("multi draw indirect: not supported")
+	}
+	if i < 0 || count < 0 || i+count > buf.len {
+		panic("multi draw indirect: index out of range")
+	}
+	buf.Begin()
+	vs.va.multiDrawIndirect(vs.primitive, i*drawIndirectCommandSize, count)
+	buf.End()
+}
+
+// BindBase binds the part of the underlying vertex buffer covered by this VertexSlice to the
+// shader storage buffer binding point index. That way, a compute shader can read or write the
+// vertices directly, without copying them through the CPU.
+//
+// Note, that the start of the VertexSlice in the underlying buffer (in bytes) must be a multiple
+// of GL_SHADER_STORAGE_BUFFER_OFFSET_ALIGNMENT, which is always true for a VertexSlice starting at
+// the index 0.
+//
+// Planar vertex slices can't be bound this way, this method panics for them.
+func (vs *VertexSlice) BindBase(index int) {
+	if vs.va.flags&planarVertexArray != 0 {
+		panic("bind base: vertex slice is planar")
+	}
+	gl.BindBufferRange(
+		gl.SHADER_STORAGE_BUFFER,
+		uint32(index),
+		vs.va.vbo.obj,
+		vs.i*vs.va.stride,
+		(vs.j-vs.i)*vs.va.stride,
+	)
+}
+
+// Delete deletes the underlying vertex array immediately instead of waiting for the garbage
+// collector, which frees its video memory right away. Note, that the vertex array is shared by all
+// VertexSlices obtained from this one by Slice, none of them must be used afterwards. Deleting a
+// VertexSlice multiple times is harmless.
+func (vs *VertexSlice) Delete() {
+	runtime.SetFinalizer(vs.va, nil)
+	vs.va.release()
 }
 
 // Begin binds the underlying vertex array. Calling this method is necessary before using the VertexSlice.
@@ -150,6 +573,19 @@ func (vs *VertexSlice) Begin() {
 	vs.va.begin()
 }
 
+// BeginShader is like Begin, but prepares the VertexSlice to be drawn with the given Shader
+// instead of the one it was created with. This way, the same vertices can be drawn in multiple
+// passes, e.g. a depth pre-pass and a color pass, without duplicating the buffers.
+//
+// Each attribute of the vertex format of the Shader must be present in the vertex format of the
+// VertexSlice with the same type, otherwise this method panics. Attributes the Shader doesn't
+// use are ignored. Instance slices attached by SetInstances only apply to the original Shader.
+//
+// Call End as usual when done.
+func (vs *VertexSlice) BeginShader(shader *Shader) {
+	vs.va.beginShader(shader)
+}
+
 // End unbinds the underlying vertex array. Call this method when you're done with VertexSlice.
 func (vs *VertexSlice) End() {
 	vs.va.end()
@@ -162,11 +598,38 @@ type vertexArray struct {
 	stride   int
 	offset   []int
 	shader   *Shader
+	usage    BufferUsage
+	flags    vertexArrayFlags
+	growth   float64
+
+	shaderVaos map[uint32]*shaderVao // vertex array objects for other compatible shaders by program
+	begun      []*binder             // vertex array objects bound by nested begins
+
+	attrVbos []uint32 // one buffer per attribute of a planar vertex array
+
+	immutable bool      // allocated with glBufferStorage, so it can't be orphaned
+	mapped    []float32 // persistently mapped buffer memory
+	fence     uintptr   // signaled when the last draw finishes
 }
 
+// shaderVao is a vertex array object set up for a Shader other than the one of the vertex array.
+// It's keyed by the program ID and not the Shader, so that the Shader can be garbage collected,
+// and the generation tells whether the program with the ID is still the same.
+type shaderVao struct {
+	gen uint32
+	vao binder
+}
+
+type vertexArrayFlags int
+
+const (
+	persistentVertexArray vertexArrayFlags = 1 << iota
+	planarVertexArray
+)
+
 const vertexArrayMinCap = 4
 
-func newVertexArray(shader *Shader, cap int) *vertexArray {
+func newVertexArray(shader *Shader, format AttrFormat, cap int, usage BufferUsage, flags vertexArrayFlags) *vertexArray {
 	if cap < vertexArrayMinCap {
 		cap = vertexArrayMinCap
 	}
@@ -185,59 +648,74 @@ func newVertexArray(shader *Shader, cap int) *vertexArray {
 			},
 		},
 		cap:    cap,
-		format: shader.VertexFormat(),
-		stride: shader.VertexFormat().Size(),
-		offset: make([]int, len(shader.VertexFormat())),
+		format: format,
+		stride: format.Size(),
+		offset: format.Offsets(),
 		shader: shader,
+		usage:  usage,
+		flags:  flags,
 	}
 
-	offset := 0
 	for i, attr := range va.format {
-		switch attr.Type {
-		case Float, Vec2, Vec3, Vec4:
-		default:
+		if _, _, _, _, ok := vertexAttribLayout(attr.Type); !ok {
 			panic(errors.New("failed to create vertex array: invalid attribute type"))
 		}
-		va.offset[i] = offset
-		offset += attr.Type.Size()
+		if va.offset[i]%4 != 0 {
+			panic(errors.New("failed to create vertex array: attribute offset not a multiple of 4"))
+		}
 	}
 
 	gl.GenVertexArrays(1, &va.vao.obj)
 
 	va.vao.bind()
 
-	gl.GenBuffers(1, &va.vbo.obj)
+	if flags&planarVertexArray != 0 && len(format) > 0 {
+		va.attrVbos = make([]uint32, len(format))
+		gl.GenBuffers(int32(len(va.attrVbos)), &va.attrVbos[0])
+		for i, attr := range format {
+			emptyData := make([]byte, cap*attr.Type.Size())
+			va.vbo.obj = va.attrVbos[i]
+			va.vbo.bind()
+			gl.BufferData(gl.ARRAY_BUFFER, len(emptyData), gl.Ptr(emptyData), uint32(va.usage))
+			va.vbo.restore()
+		}
+		// the first buffer stands for the whole vertex array when binding
+		va.vbo.obj = va.attrVbos[0]
+	} else {
+		gl.GenBuffers(1, &va.vbo.obj)
+		flags &^= planarVertexArray
+		va.flags = flags
+	}
 	defer va.vbo.bind().restore()
 
-	emptyData := make([]byte, cap*va.stride)
-	gl.BufferData(gl.ARRAY_BUFFER, len(emptyData), gl.Ptr(emptyData), gl.DYNAMIC_DRAW)
+	// data streamed every frame is orphaned when rewritten, which needs mutable storage, persistent
+	// mapping is opt-in by MakePersistentVertexSlice
+	immutable := bufferStorageSupported() && !WorkaroundActive(AvoidBufferStorage) && usage != StreamDraw
 
-	for i, attr := range va.format {
-		loc := gl.GetAttribLocation(shader.program.obj, gl.Str(attr.Name+"\x00"))
-
-		var size int32
-		switch attr.Type {
-		case Float:
-			size = 1
-		case Vec2:
-			size = 2
-		case Vec3:
-			size = 3
-		case Vec4:
-			size = 4
+	switch {
+	case flags&planarVertexArray != 0:
+		// already allocated
+	case flags&persistentVertexArray != 0:
+		emptyData := make([]byte, cap*va.stride)
+		flags := uint32(gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT)
+		gl.BufferStorage(gl.ARRAY_BUFFER, len(emptyData), gl.Ptr(emptyData), flags|gl.DYNAMIC_STORAGE_BIT)
+		ptr := gl.MapBufferRange(gl.ARRAY_BUFFER, 0, len(emptyData), flags)
+		if ptr == nil {
+			panic(errors.New("failed to create vertex array: failed to map the buffer"))
 		}
-
-		gl.VertexAttribPointerWithOffset(
-			uint32(loc),
-			size,
-			gl.FLOAT,
-			false,
-			int32(va.stride),
-			uintptr(va.offset[i]),
-		)
-		gl.EnableVertexAttribArray(uint32(loc))
+		va.mapped = unsafe.Slice((*float32)(ptr), len(emptyData)/4)
+	case immutable:
+		emptyData := make([]byte, cap*va.stride)
+		flags := uint32(gl.DYNAMIC_STORAGE_BIT | gl.MAP_WRITE_BIT | gl.MAP_READ_BIT)
+		gl.BufferStorage(gl.ARRAY_BUFFER, len(emptyData), gl.Ptr(emptyData), flags)
+		va.immutable = true
+	default:
+		emptyData := make([]byte, cap*va.stride)
+		gl.BufferData(gl.ARRAY_BUFFER, len(emptyData), gl.Ptr(emptyData), uint32(va.usage))
 	}
 
+	va.attribPointers(va.shader, 0, 0)
+
 	va.vao.restore()
 
 	runtime.SetFinalizer(va, (*vertexArray).delete)
@@ -246,24 +724,236 @@ func newVertexArray(shader *Shader, cap int) *vertexArray {
 }
 
 func (va *vertexArray) delete() {
-	mainthread.CallNonBlock(func() {
-		gl.DeleteVertexArrays(1, &va.vao.obj)
+	mainthread.CallNonBlock(va.release)
+}
+
+func (va *vertexArray) release() {
+	gl.DeleteVertexArrays(1, &va.vao.obj)
+	va.vao.invalidate()
+	for _, sv := range va.shaderVaos {
+		gl.DeleteVertexArrays(1, &sv.vao.obj)
+	}
+	va.shaderVaos = nil
+	if va.attrVbos != nil {
+		gl.DeleteBuffers(int32(len(va.attrVbos)), &va.attrVbos[0])
+		va.attrVbos = nil
+	} else {
 		gl.DeleteBuffers(1, &va.vbo.obj)
-	})
+	}
+	va.vbo.invalidate()
+	va.mapped = nil
+	if va.fence != 0 {
+		gl.DeleteSync(va.fence)
+		va.fence = 0
+	}
+}
+
+// attribPointers points the attributes of the currently bound vertex array object to the vertex
+// buffer of va, starting at the given byte offset, using the attribute locations of the shader.
+// The vertex buffer of va must be bound.
+func (va *vertexArray) attribPointers(shader *Shader, offset int, divisor uint32) {
+	for i, attr := range va.format {
+		loc := gl.GetAttribLocation(shader.program.obj, gl.Str(attr.Name+"\x00"))
+		if loc < 0 {
+			// the attribute is not used by the shader
+			continue
+		}
+
+		size, xtype, normalized, integer, _ := vertexAttribLayout(attr.Type)
+
+		stride, attrOffset := va.stride, offset+va.offset[i]
+		if va.attrVbos != nil {
+			// each attribute is tightly packed in its own buffer
+			gl.BindBuffer(gl.ARRAY_BUFFER, va.attrVbos[i])
+			stride, attrOffset = attr.Type.Size(), offset/va.stride*attr.Type.Size()
+		}
+
+		if !integer {
+			gl.VertexAttribPointerWithOffset(
+				uint32(loc),
+				size,
+				xtype,
+				normalized,
+				int32(stride),
+				uintptr(attrOffset),
+			)
+		} else {
+			// integer attributes must not be converted to floats
+			gl.VertexAttribIPointer(
+				uint32(loc),
+				size,
+				xtype,
+				int32(stride),
+				gl.PtrOffset(attrOffset),
+			)
+		}
+		gl.VertexAttribDivisor(uint32(loc), divisor)
+		gl.EnableVertexAttribArray(uint32(loc))
+	}
+
+	if va.attrVbos != nil {
+		gl.BindBuffer(gl.ARRAY_BUFFER, va.vbo.obj)
+	}
+}
+
+// vertexAttribLayout returns the number of components and their OpenGL type for a vertex
+// attribute type, along with whether the components are normalized and whether they're passed to
+// the shader as integers. If the type can't be used as a vertex attribute, ok is false.
+func vertexAttribLayout(at AttrType) (size int32, xtype uint32, normalized, integer, ok bool) {
+	switch at {
+	case Float:
+		return 1, gl.FLOAT, false, false, true
+	case Vec2:
+		return 2, gl.FLOAT, false, false, true
+	case Vec3:
+		return 3, gl.FLOAT, false, false, true
+	case Vec4:
+		return 4, gl.FLOAT, false, false, true
+	case Int:
+		return 1, gl.INT, false, true, true
+	case IVec2:
+		return 2, gl.INT, false, true, true
+	case IVec3:
+		return 3, gl.INT, false, true, true
+	case IVec4:
+		return 4, gl.INT, false, true, true
+	case Uint:
+		return 1, gl.UNSIGNED_INT, false, true, true
+	case UVec2:
+		return 2, gl.UNSIGNED_INT, false, true, true
+	case UVec3:
+		return 3, gl.UNSIGNED_INT, false, true, true
+	case UVec4:
+		return 4, gl.UNSIGNED_INT, false, true, true
+	case UByte4Norm:
+		return 4, gl.UNSIGNED_BYTE, true, false, true
+	case Byte4Norm:
+		return 4, gl.BYTE, true, false, true
+	case UShort2Norm:
+		return 2, gl.UNSIGNED_SHORT, true, false, true
+	case UShort4Norm:
+		return 4, gl.UNSIGNED_SHORT, true, false, true
+	case Short2Norm:
+		return 2, gl.SHORT, true, false, true
+	case Short4Norm:
+		return 4, gl.SHORT, true, false, true
+	case Half2:
+		return 2, gl.HALF_FLOAT, false, false, true
+	case Half4:
+		return 4, gl.HALF_FLOAT, false, false, true
+	default:
+		return 0, 0, false, false, false
+	}
 }
 
 func (va *vertexArray) begin() {
+	va.begun = append(va.begun, &va.vao)
 	va.vao.bind()
 	va.vbo.bind()
 }
 
+func (va *vertexArray) beginShader(shader *Shader) {
+	if shader == va.shader {
+		va.begin()
+		return
+	}
+
+	sv := va.shaderVaos[shader.program.obj]
+	if sv != nil && sv.gen != shader.program.gen {
+		// the program was deleted and its ID reused by another one
+		gl.DeleteVertexArrays(1, &sv.vao.obj)
+		delete(va.shaderVaos, shader.program.obj)
+		sv = nil
+	}
+	if sv == nil {
+		for _, attr := range shader.VertexFormat() {
+			compatible := false
+			for _, own := range va.format {
+				if own.Name == attr.Name && own.Type == attr.Type {
+					compatible = true
+					break
+				}
+			}
+			if !compatible {
+				panic("begin shader: incompatible vertex format")
+			}
+		}
+
+		sv = &shaderVao{
+			gen: shader.program.gen,
+			vao: binder{
+				restoreLoc: gl.VERTEX_ARRAY_BINDING,
+				bindFunc: func(obj uint32) {
+					gl.BindVertexArray(obj)
+				},
+			},
+		}
+		gl.GenVertexArrays(1, &sv.vao.obj)
+		sv.vao.bind()
+		va.vbo.bind()
+		va.attribPointers(shader, 0, 0)
+		va.vbo.restore()
+		sv.vao.restore()
+
+		if va.shaderVaos == nil {
+			va.shaderVaos = make(map[uint32]*shaderVao)
+		}
+		va.shaderVaos[shader.program.obj] = sv
+	}
+
+	vao := &sv.vao
+	va.begun = append(va.begun, vao)
+	vao.bind()
+	va.vbo.bind()
+}
+
 func (va *vertexArray) end() {
+	vao := va.begun[len(va.begun)-1]
+	va.begun = va.begun[:len(va.begun)-1]
 	va.vbo.restore()
-	va.vao.restore()
+	vao.restore()
+}
+
+func (va *vertexArray) draw(primitive Primitive, i, j int) {
+	gl.DrawArrays(primitive.glMode(), int32(i), int32(j-i))
+	va.placeFence()
 }
 
-func (va *vertexArray) draw(i, j int) {
-	gl.DrawArrays(gl.TRIANGLES, int32(i), int32(j-i))
+func (va *vertexArray) drawInstanced(primitive Primitive, i, j, count int) {
+	gl.DrawArraysInstanced(primitive.glMode(), int32(i), int32(j-i), int32(count))
+	va.placeFence()
+}
+
+func (va *vertexArray) multiDrawIndirect(primitive Primitive, offset, count int) {
+	gl.MultiDrawArraysIndirect(primitive.glMode(), gl.PtrOffset(offset), int32(count), 0)
+	va.placeFence()
+}
+
+// placeFence replaces the fence of a persistently mapped vertex array with one signaled after
+// all commands issued so far. It does nothing for other vertex arrays.
+func (va *vertexArray) placeFence() {
+	if va.mapped == nil {
+		return
+	}
+	if va.fence != 0 {
+		gl.DeleteSync(va.fence)
+	}
+	va.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+}
+
+// waitFence waits until the fence placed by the last draw is signaled.
+func (va *vertexArray) waitFence() {
+	if va.fence == 0 {
+		return
+	}
+	for {
+		status := gl.ClientWaitSync(va.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1e9)
+		if status != gl.TIMEOUT_EXPIRED {
+			break
+		}
+	}
+	gl.DeleteSync(va.fence)
+	va.fence = 0
 }
 
 func (va *vertexArray) setVertexData(i, j int, data []float32) {
@@ -271,15 +961,143 @@ func (va *vertexArray) setVertexData(i, j int, data []float32) {
 		// avoid setting 0 bytes of buffer data
 		return
 	}
+	if va.attrVbos != nil {
+		// split the interleaved vertices into the attribute buffers
+		for attr := range va.format {
+			words := va.format[attr].Type.Size() / 4
+			attrData := make([]float32, 0, (j-i)*words)
+			for v := 0; v < j-i; v++ {
+				start := v*va.stride/4 + va.offset[attr]/4
+				attrData = append(attrData, data[start:start+words]...)
+			}
+			va.setAttrData(attr, i, attrData)
+		}
+		return
+	}
 	gl.BufferSubData(gl.ARRAY_BUFFER, i*va.stride, len(data)*4, gl.Ptr(data))
 }
 
+func (va *vertexArray) streamVertexData(i, j int, data []float32) {
+	if j-i == 0 {
+		// avoid setting 0 bytes of buffer data
+		return
+	}
+
+	if va.attrVbos != nil {
+		va.setVertexData(i, j, data)
+		return
+	}
+
+	if va.mapped != nil {
+		// persistently mapped buffers can't be orphaned, just wait for the GPU
+		va.waitFence()
+		copy(va.mapped[i*va.stride/4:], data)
+		return
+	}
+
+	if i == 0 && j == va.cap && va.immutable && invalidateSupported() {
+		// immutable storage can't be reallocated, but it can be invalidated just the same
+		gl.InvalidateBufferData(va.vbo.obj)
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(data)*4, gl.Ptr(data))
+		return
+	}
+
+	if i == 0 && j == va.cap && !va.immutable {
+		// orphan the whole buffer
+		gl.BufferData(gl.ARRAY_BUFFER, va.cap*va.stride, nil, uint32(va.usage))
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(data)*4, gl.Ptr(data))
+		return
+	}
+
+	if WorkaroundActive(AvoidMapBufferRange) {
+		gl.BufferSubData(gl.ARRAY_BUFFER, i*va.stride, len(data)*4, gl.Ptr(data))
+		return
+	}
+
+	ptr := gl.MapBufferRange(
+		gl.ARRAY_BUFFER,
+		i*va.stride,
+		len(data)*4,
+		gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_RANGE_BIT,
+	)
+	if ptr == nil {
+		// mapping failed, fall back to a plain upload
+		gl.BufferSubData(gl.ARRAY_BUFFER, i*va.stride, len(data)*4, gl.Ptr(data))
+		return
+	}
+	copy(unsafe.Slice((*float32)(ptr), len(data)), data)
+	gl.UnmapBuffer(gl.ARRAY_BUFFER)
+}
+
+func (va *vertexArray) mapVertexData(i, j int, fn func([]float32)) {
+	n := (j - i) * va.stride / 4
+	if n == 0 {
+		// avoid mapping 0 bytes of buffer data
+		fn(nil)
+		return
+	}
+
+	if va.attrVbos != nil || WorkaroundActive(AvoidMapBufferRange) {
+		data := va.vertexData(i, j)
+		fn(data)
+		va.setVertexData(i, j, data)
+		return
+	}
+
+	ptr := gl.MapBufferRange(gl.ARRAY_BUFFER, i*va.stride, n*4, gl.MAP_WRITE_BIT)
+	if ptr == nil {
+		// mapping failed, fall back to a copy
+		data := va.vertexData(i, j)
+		fn(data)
+		va.setVertexData(i, j, data)
+		return
+	}
+	fn(unsafe.Slice((*float32)(ptr), n))
+	gl.UnmapBuffer(gl.ARRAY_BUFFER)
+}
+
 func (va *vertexArray) vertexData(i, j int) []float32 {
 	if j-i == 0 {
 		// avoid getting 0 bytes of buffer data
 		return nil
 	}
 	data := make([]float32, (j-i)*va.stride/4)
+	if va.attrVbos != nil {
+		// merge the attribute buffers into interleaved vertices
+		for attr := range va.format {
+			words := va.format[attr].Type.Size() / 4
+			attrData := va.attrData(attr, i, j)
+			for v := 0; v < j-i; v++ {
+				start := v*va.stride/4 + va.offset[attr]/4
+				copy(data[start:start+words], attrData[v*words:])
+			}
+		}
+		return data
+	}
 	gl.GetBufferSubData(gl.ARRAY_BUFFER, i*va.stride, len(data)*4, gl.Ptr(data))
 	return data
 }
+
+func (va *vertexArray) setAttrData(attr, i int, data []float32) {
+	if len(data) == 0 {
+		// avoid setting 0 bytes of buffer data
+		return
+	}
+	size := va.format[attr].Type.Size()
+	gl.BindBuffer(gl.ARRAY_BUFFER, va.attrVbos[attr])
+	gl.BufferSubData(gl.ARRAY_BUFFER, i*size, len(data)*4, gl.Ptr(data))
+	gl.BindBuffer(gl.ARRAY_BUFFER, va.vbo.obj)
+}
+
+func (va *vertexArray) attrData(attr, i, j int) []float32 {
+	if j-i == 0 {
+		// avoid getting 0 bytes of buffer data
+		return nil
+	}
+	size := va.format[attr].Type.Size()
+	data := make([]float32, (j-i)*size/4)
+	gl.BindBuffer(gl.ARRAY_BUFFER, va.attrVbos[attr])
+	gl.GetBufferSubData(gl.ARRAY_BUFFER, i*size, len(data)*4, gl.Ptr(data))
+	gl.BindBuffer(gl.ARRAY_BUFFER, va.vbo.obj)
+	return data
+}